@@ -0,0 +1,32 @@
+package writesplitter
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrUnsupported is returned by attribute helpers such as Immutable and Hidden
+// on platforms that have no such attribute
+var ErrUnsupported = errors.New("WriteSplitter: attribute not supported on this platform")
+
+// Attrs returns an OnFinalized func that applies each of fns to the file in
+// turn, stopping at the first error, e.g. Attrs(ReadOnly, Immutable)
+func Attrs(fns ...func(name string) error) func(name string) error {
+	return func(name string) error {
+		for _, fn := range fns {
+			if e := fn(name); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+}
+
+// ReadOnly takes away write permission on the named file from everyone
+func ReadOnly(name string) error {
+	info, e := os.Stat(name)
+	if e != nil {
+		return e
+	}
+	return os.Chmod(name, info.Mode().Perm()&^0222)
+}
@@ -0,0 +1,139 @@
+package writesplitter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Barrier rotates, as Rotate does, and then blocks until every file created
+// before the call, streams included, has been finalized, OnClosed and all,
+// and synced to disk in its final form: Footer, compression and sidecars
+// included. Files completed since the last Barrier are synced along with the
+// one it rotates; each file is synced by only one Barrier. This gives backup tools a consistent cut of the series. Writes
+// may continue into the new files while Barrier waits. If ctx is done first,
+// Barrier returns ctx.Err(); the rotation has still happened. Files from a
+// Factory are closed but not synced, that's up to the Factory.
+func (ws *WriteSplitter) Barrier(ctx context.Context) error {
+	ws.mu.Lock()
+	ws.setBarrier(true)
+	ws.ctx = ctx
+	e := ws.rotate()
+	ws.ctx = nil
+	ws.setBarrier(false)
+	pending := []chan struct{}{ws.lastFinal()}
+	all := []*WriteSplitter{ws}
+	for _, s := range ws.streams {
+		pending = append(pending, s.lastFinal())
+		all = append(all, s)
+	}
+	ws.mu.Unlock()
+
+	for _, done := range pending {
+		if done == nil {
+			continue
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for _, s := range all {
+		if fe := s.finalError(); e == nil {
+			e = fe
+		}
+		s.mu.Lock()
+		se := s.syncFinalized()
+		s.mu.Unlock()
+		if e == nil {
+			e = se
+		}
+	}
+	return e
+}
+
+// syncFinalized syncs every finalized file not yet known to be durable, see
+// Barrier. A file that fails to sync is tried again by the next Barrier.
+func (ws *WriteSplitter) syncFinalized() error {
+	if ws.Factory != nil {
+		return nil
+	}
+	var err error
+	for i := range ws.files {
+		rec := &ws.files[i]
+		if rec.durable || !ws.finalized(rec.name) {
+			continue
+		}
+		if e := ws.syncFinal(*rec); e != nil && !os.IsNotExist(e) { // e.g. removed by RemoveEmpty in the meantime
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		rec.durable = true
+	}
+	return err
+}
+
+// finalError returns the first error from a background finalize, leaving it
+// for wait to return as well
+func (ws *WriteSplitter) finalError() error {
+	ws.finalMu.Lock()
+	defer ws.finalMu.Unlock()
+	return ws.finalErr
+}
+
+// setBarrier marks the files of ws and its streams closed from now on as to
+// be synced, or not
+func (ws *WriteSplitter) setBarrier(on bool) {
+	ws.barrier = on
+	for _, s := range ws.streams {
+		s.mu.Lock()
+		s.barrier = on
+		s.mu.Unlock()
+	}
+}
+
+// syncFinal syncs the finalized file rec, its raw copy and sidecars if any,
+// and the dir holding them so their names are durable too
+func (ws *WriteSplitter) syncFinal(rec fileRecord) error {
+	names := []string{rec.name}
+	if rec.gzipped && ws.KeepRaw {
+		names = append(names, strings.TrimSuffix(rec.name, ".gz"))
+	}
+	for _, side := range ws.sidecars(rec.name) {
+		if _, e := os.Stat(side); e == nil {
+			names = append(names, side)
+		}
+	}
+	for _, name := range names {
+		if e := syncFile(name, os.O_WRONLY); e != nil { // windows only syncs files open for writing
+			return e
+		}
+	}
+	syncFile(filepath.Dir(rec.name), os.O_RDONLY) // not every platform can sync a dir
+	return nil
+}
+
+// syncFile commits the named file to disk, opening it with flag
+func syncFile(name string, flag int) error {
+	f, e := os.OpenFile(name, flag, 0)
+	if e != nil {
+		return e
+	}
+	e = f.Sync()
+	if ce := f.Close(); e == nil {
+		e = ce
+	}
+	return e
+}
+
+// lastFinal returns the channel closed once the most recent background
+// finalize is done, nil if there never was one
+func (ws *WriteSplitter) lastFinal() chan struct{} {
+	ws.finalMu.Lock()
+	defer ws.finalMu.Unlock()
+	return ws.finalLast
+}
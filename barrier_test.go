@@ -0,0 +1,55 @@
+package writesplitter
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBarrierFinalizes(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "x")
+	ws.Compress = true
+	ws.Footer = func(FileInfo) []byte { return []byte("end\n") }
+	ws.Write([]byte("a\n"))
+	ws.Stream("err").Write([]byte("b\n"))
+	if e := ws.Barrier(context.Background()); e != nil {
+		t.Fatal(e)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var gz int
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".gz") {
+			gz++
+		}
+	}
+	if gz != 2 {
+		t.Errorf("got %d compressed files once Barrier returned, want 2", gz)
+	}
+	ws.Close()
+}
+
+func TestBarrierSyncsEarlierFiles(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(1, dir, "x")
+	for _, s := range []string{"a\n", "b\n", "c\n"} { // two files completed before the Barrier
+		ws.Write([]byte(s))
+	}
+	if e := ws.Barrier(context.Background()); e != nil {
+		t.Fatal(e)
+	}
+
+	ws.mu.Lock()
+	for _, rec := range ws.files[:len(ws.files)-1] {
+		if !rec.durable {
+			t.Errorf("%s not synced", rec.name)
+		}
+	}
+	if last := ws.files[len(ws.files)-1]; last.durable {
+		t.Error("the open file is marked as synced")
+	}
+	ws.mu.Unlock()
+	ws.Close()
+}
@@ -0,0 +1,64 @@
+package writesplitter
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// Flush writes any data held in the buffer out to the open file, see Buffer
+func (ws *WriteSplitter) Flush() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.flush()
+}
+
+// flush is Flush without the locking
+func (ws *WriteSplitter) flush() error {
+	if ws.buf == nil {
+		return nil
+	}
+	return ws.buf.Flush()
+}
+
+// out returns where writes to the open file go, through the buffer if any
+func (ws *WriteSplitter) out() io.Writer {
+	if ws.buf != nil {
+		return ws.buf
+	}
+	return ws.handle
+}
+
+// buffer points the buffer, if Buffer asks for one, at the newly created f
+func (ws *WriteSplitter) buffer(f io.Writer) {
+	switch {
+	case ws.Buffer <= 0:
+		ws.buf = nil
+	case ws.buf == nil || ws.buf.Size() != ws.Buffer:
+		ws.buf = bufio.NewWriterSize(f, ws.Buffer)
+	default:
+		ws.buf.Reset(f)
+	}
+}
+
+// autoFlush arranges for the buffer to be flushed FlushEvery from now, unless
+// that's already arranged
+func (ws *WriteSplitter) autoFlush() {
+	if ws.buf == nil || ws.FlushEvery <= 0 || ws.flushTimer != nil {
+		return
+	}
+	ws.flushTimer = time.AfterFunc(ws.FlushEvery, func() {
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		ws.flushTimer = nil
+		ws.report(ws.flush())
+	})
+}
+
+// stopFlush cancels a pending autoFlush
+func (ws *WriteSplitter) stopFlush() {
+	if ws.flushTimer != nil {
+		ws.flushTimer.Stop()
+		ws.flushTimer = nil
+	}
+}
@@ -0,0 +1,35 @@
+package writesplitter
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBufferAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "x")
+	ws.Buffer = 64
+	ws.Write([]byte("one\n"))
+	if e := ws.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := ws.Write([]byte("two\n")); e != ErrClosed {
+		t.Errorf("Write after Close: got %v, want ErrClosed", e)
+	}
+	if e := ws.Flush(); e != nil {
+		t.Errorf("Flush after Close: %v", e)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got %d files, want 1", len(entries))
+	}
+	if b, _ := os.ReadFile(ws.Files()[0]); string(b) != "one\n" {
+		t.Errorf("file holds %q", b)
+	}
+
+	ws.Reset()
+	if _, e := ws.Write([]byte("three\n")); e != nil {
+		t.Errorf("Write after Reset: %v", e)
+	}
+	ws.Close()
+}
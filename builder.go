@@ -0,0 +1,150 @@
+package writesplitter
+
+import (
+	"hash"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+// Builder assembles a WriteSplitter one step at a time, e.g.
+//
+//	ws, err := Build().InDir("/var/log/app").SplitByBytes(100 << 20).WithRetention(policy).New()
+//
+// The configuration is validated when New is called.
+type Builder struct {
+	ws  WriteSplitter
+	err error // from a step that couldn't be taken, returned by New
+}
+
+// Build starts a new Builder for a WriteSplitter that writes to the working
+// dir and never splits
+func Build() *Builder {
+	return &Builder{}
+}
+
+// SplitByLines splits files every n writes
+func (b *Builder) SplitByLines(n int) *Builder {
+	b.ws.Limit, b.ws.Bytes = n, false
+	return b
+}
+
+// SplitByBytes splits files every n bytes
+func (b *Builder) SplitByBytes(n int) *Builder {
+	b.ws.Limit, b.ws.Bytes = n, true
+	return b
+}
+
+// SplitBy splits files per a limit as understood by ParseLimit, e.g. "100MB"
+// or "50k lines"
+func (b *Builder) SplitBy(limit string) *Builder {
+	n, bytes, e := ParseLimit(limit)
+	if e != nil {
+		if b.err == nil {
+			b.err = e
+		}
+		return b
+	}
+	b.ws.Limit, b.ws.Bytes = n, bytes
+	return b
+}
+
+// SplitEvery splits files once they've been written to for d
+func (b *Builder) SplitEvery(d time.Duration) *Builder {
+	b.ws.Interval = d
+	return b
+}
+
+// SplitAt splits files at the times next returns, see
+// WriteSplitter.NextRotation
+func (b *Builder) SplitAt(next func(opened time.Time) time.Time) *Builder {
+	b.ws.NextRotation = next
+	return b
+}
+
+// RotateWhen rotates files as p decides, in place of a limit or interval,
+// see WriteSplitter.Rotation
+func (b *Builder) RotateWhen(p splitcore.RotationPolicy) *Builder {
+	b.ws.Rotation = p
+	return b
+}
+
+// InDir sets the dir files are created in
+func (b *Builder) InDir(dir string) *Builder {
+	b.ws.Dir = filepath.Clean(dir)
+	return b
+}
+
+// WithPrefix sets the prefix of every filename
+func (b *Builder) WithPrefix(prefix string) *Builder {
+	b.ws.Prefix = filepath.Clean(prefix)
+	return b
+}
+
+// Hashed computes a digest of every file with fn, see WriteSplitter.Hash
+func (b *Builder) Hashed(fn func() hash.Hash) *Builder {
+	b.ws.Hash = fn
+	return b
+}
+
+// Compressed gzips rotated files in the background
+func (b *Builder) Compressed() *Builder {
+	b.ws.Compress = true
+	return b
+}
+
+// Background finalizes rotated files off the Write path
+func (b *Builder) Background() *Builder {
+	b.ws.Async = true
+	return b
+}
+
+// ShipWith hands every completed file to fn, see WriteSplitter.OnClosed
+func (b *Builder) ShipWith(fn func(name string, r io.Reader) error) *Builder {
+	b.ws.OnClosed = fn
+	return b
+}
+
+// WarmTo moves completed files to dir once older than after or while the
+// files in Dir total more than hotBytes, either of which may be zero
+func (b *Builder) WarmTo(dir string, after time.Duration, hotBytes int64) *Builder {
+	b.ws.WarmDir, b.ws.WarmAfter, b.ws.HotBytes = dir, after, hotBytes
+	return b
+}
+
+// WithRetention deletes completed files chosen by p
+func (b *Builder) WithRetention(p RetentionPolicy) *Builder {
+	b.ws.Retention = p
+	return b
+}
+
+// EncryptedWith encrypts every file with key, see WriteSplitter.Key
+func (b *Builder) EncryptedWith(key []byte) *Builder {
+	b.ws.Key = key
+	return b
+}
+
+// With applies opts, for settings that have an Option but no step of their own
+func (b *Builder) With(opts ...Option) *Builder {
+	for _, opt := range opts {
+		if e := opt(&b.ws); e != nil && b.err == nil {
+			b.err = e
+		}
+	}
+	return b
+}
+
+// New validates the configuration and returns the WriteSplitter, or the
+// first error from a step such as SplitBy
+func (b *Builder) New() (*WriteSplitter, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	ws := b.ws.clone()
+	if e := ws.Validate(); e != nil {
+		return nil, e
+	}
+	return ws, nil
+}
@@ -0,0 +1,11 @@
+package writesplitter
+
+import "os/exec"
+
+// Capture wires the stdout and stderr of cmd into the "stdout" and "stderr"
+// streams of ws, see Stream, so both are split and rotated together. It must
+// be called before cmd is started; ws should be closed once cmd is done.
+func Capture(cmd *exec.Cmd, ws *WriteSplitter) {
+	cmd.Stdout = ws.Stream("stdout")
+	cmd.Stderr = ws.Stream("stderr")
+}
@@ -0,0 +1,56 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCollisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string // files already there
+		temp  string
+		want  string // name of the file created, "" if none can be
+	}{
+		{"free", nil, "", "fixed.log"},
+		{"taken", []string{"fixed.log"}, "", "fixed.log-1"},
+		{"taken twice", []string{"fixed.log", "fixed.log-1"}, "", "fixed.log-2"},
+		{"final name taken", []string{"fixed.log"}, ".tmp", "fixed.log-1.tmp"},
+		{"temp name taken", []string{"fixed.log.tmp"}, ".tmp", "fixed.log-1.tmp"},
+		{"all taken", allTaken(), "", ""},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, name := range tt.taken {
+			os.WriteFile(filepath.Join(dir, name), []byte("theirs"), 0666)
+		}
+		ws := LineSplitter(0, dir, "x")
+		ws.Template, ws.TempSuffix = "fixed.log", tt.temp
+
+		_, e := ws.Write([]byte("ours"))
+		if got := filepath.Base(ws.CurrentFile()); tt.want != "" && (e != nil || got != tt.want) {
+			t.Errorf("%s: created %s, %v, want %s", tt.name, got, e, tt.want)
+		}
+		if tt.want == "" && !os.IsExist(e) {
+			t.Errorf("%s: got %v, want the name to be taken", tt.name, e)
+		}
+		ws.Close()
+
+		for _, name := range tt.taken {
+			if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != "theirs" {
+				t.Errorf("%s: %s was overwritten", tt.name, name)
+			}
+		}
+	}
+}
+
+// allTaken returns every name open tries for fixed.log
+func allTaken() []string {
+	names := []string{"fixed.log"}
+	for i := 1; i <= maxCollisions; i++ {
+		names = append(names, "fixed.log-"+strconv.Itoa(i))
+	}
+	return names
+}
@@ -0,0 +1,39 @@
+package writesplitter
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// gzipFile compresses src into dst, created with perm, and removes src unless
+// keep is set. If anything goes wrong dst is removed and src left as is.
+func gzipFile(src, dst string, perm os.FileMode, keep bool) error {
+	in, e := os.Open(src)
+	if e != nil {
+		return e
+	}
+	defer in.Close()
+
+	out, e := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if e != nil {
+		return e
+	}
+
+	zw := gzip.NewWriter(out)
+	_, e = io.Copy(zw, in)
+	if ce := zw.Close(); e == nil {
+		e = ce
+	}
+	if ce := out.Close(); e == nil {
+		e = ce
+	}
+	if e != nil {
+		os.Remove(dst)
+		return e
+	}
+	if keep {
+		return nil
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,44 @@
+package writesplitter
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestShouldCompress(t *testing.T) {
+	ws := LineSplitter(1, t.TempDir(), "x")
+	ws.Compress = true
+	ws.ShouldCompress = func(fi FileInfo) bool { return fi.Size > 5 }
+	for _, s := range []string{"tiny\n", "not so tiny\n", "last\n"} {
+		ws.Write([]byte(s))
+	}
+	ws.Close()
+
+	files := ws.Files()
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+	for i, want := range []bool{false, true, false} { // the last isn't rotated, so never compressed
+		if got := strings.HasSuffix(files[i], ".gz"); got != want {
+			t.Errorf("%s: compressed %v, want %v", files[i], got, want)
+		}
+	}
+}
+
+func TestDropCacheCompressed(t *testing.T) {
+	ws := LineSplitter(1, t.TempDir(), "x")
+	ws.Compress, ws.KeepRaw, ws.DropCache = true, true, true
+	ws.Write([]byte("a\n"))
+	ws.Write([]byte("b\n"))
+	ws.Close()
+
+	rr := NewRecordReader(ws.Files()...)
+	defer rr.Close()
+	if rec, _ := rr.Next(); string(rec) != "a" {
+		t.Errorf("got %q", rec)
+	}
+	if b, _ := os.ReadFile(strings.TrimSuffix(ws.Files()[0], ".gz")); string(b) != "a\n" {
+		t.Errorf("raw copy holds %q", b)
+	}
+}
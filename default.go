@@ -0,0 +1,40 @@
+package writesplitter
+
+import "sync"
+
+var (
+	stdMu sync.RWMutex
+	std   *WriteSplitter
+)
+
+// SetDefault sets the package level WriteSplitter used by Write and Rotate
+func SetDefault(ws *WriteSplitter) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std = ws
+}
+
+// Default returns the package level WriteSplitter, nil if none has been set
+func Default() *WriteSplitter {
+	stdMu.RLock()
+	defer stdMu.RUnlock()
+	return std
+}
+
+// Write writes p to the default WriteSplitter
+func Write(p []byte) (int, error) {
+	ws := Default()
+	if ws == nil {
+		return 0, ErrNoDefault
+	}
+	return ws.Write(p)
+}
+
+// Rotate rotates the default WriteSplitter
+func Rotate() error {
+	ws := Default()
+	if ws == nil {
+		return ErrNoDefault
+	}
+	return ws.Rotate()
+}
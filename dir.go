@@ -0,0 +1,120 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MissingDirPolicy decides what a WriteSplitter does when the dir it's
+// creating a file in no longer exists
+type MissingDirPolicy int
+
+// The MissingDirPolicy values
+const (
+	FailMissingDir     MissingDirPolicy = iota // fail the Write with ErrDirGone
+	RecreateMissingDir                         // create the dir again
+	FallbackMissingDir                         // switch to FallbackDir for good
+)
+
+// reopen retries creating the file called name after its dir went missing,
+// as decided by MissingDir
+func (ws *WriteSplitter) reopen(filename, name string, flag int) (*os.File, string, error) {
+	policy := ws.MissingDir
+	if ws.MkdirAll {
+		policy = RecreateMissingDir
+	}
+
+	switch policy {
+	case RecreateMissingDir:
+		if e := os.MkdirAll(filepath.Dir(filename), ws.dirMode()); e != nil {
+			return nil, filename, e
+		}
+	case FallbackMissingDir:
+		if ws.FallbackDir == "" {
+			return nil, filename, ErrDirGone
+		}
+		ws.Dir = filepath.Clean(ws.FallbackDir)
+		filename = filepath.Join(ws.Dir, name)
+	default:
+		return nil, filename, ErrDirGone
+	}
+
+	f, e := os.OpenFile(filename, flag, ws.fileMode())
+	return f, filename, e
+}
+
+// fileMode returns the permissions new files are created with
+func (ws *WriteSplitter) fileMode() os.FileMode {
+	if ws.FileMode == 0 {
+		return 0666
+	}
+	return ws.FileMode
+}
+
+// dirMode returns the permissions new dirs are created with
+func (ws *WriteSplitter) dirMode() os.FileMode {
+	if ws.DirMode == 0 {
+		return 0755
+	}
+	return ws.DirMode
+}
+
+// expandDir replaces the tokens in dir using t and the host name:
+//
+//	%Y year, %m month, %d day, %H hour, %M minute, %S second (zero padded)
+//	%h host name
+//	%% a literal %
+//
+// Unknown tokens are left as is.
+func expandDir(dir string, t time.Time) string {
+	if !strings.Contains(dir, "%") {
+		return dir
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(dir); i++ {
+		if dir[i] != '%' || i == len(dir)-1 {
+			b.WriteByte(dir[i])
+			continue
+		}
+
+		i++
+		switch dir[i] {
+		case 'Y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case 'm':
+			pad2(&b, int(t.Month()))
+		case 'd':
+			pad2(&b, t.Day())
+		case 'H':
+			pad2(&b, t.Hour())
+		case 'M':
+			pad2(&b, t.Minute())
+		case 'S':
+			pad2(&b, t.Second())
+		case 'h':
+			host, e := os.Hostname()
+			if e != nil {
+				host = "unknown"
+			}
+			b.WriteString(host)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(dir[i])
+		}
+	}
+	return b.String()
+}
+
+// pad2 writes n to b as at least two digits
+func pad2(b *strings.Builder, n int) {
+	if n < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.Itoa(n))
+}
@@ -0,0 +1,228 @@
+package writesplitter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Encrypted files start with encMagic and a random nonce prefix, followed by
+// frames of a 4 byte big endian length and that many bytes sealed with
+// AES-GCM. Each frame is one Write. The nonce of a frame is the prefix and its
+// index, and the final frame, written by Close, is empty and sealed with
+// different additional data, so a file cut short or reordered won't decrypt.
+const (
+	encMagic    = "WSE1"
+	encPrefix   = 8
+	encMaxFrame = 1 << 20 // plaintext bytes per frame, longer Writes are split
+)
+
+var (
+	encMore = []byte{0} // additional data of every frame but the last
+	encLast = []byte{1}
+)
+
+// ErrTruncated is returned by a Decrypt reader for a file that doesn't end
+// with the final frame written by Close
+var ErrTruncated = errors.New("WriteSplitter: encrypted file is truncated")
+
+// ErrNotEncrypted is returned by Decrypt for a file that isn't encrypted
+var ErrNotEncrypted = errors.New("WriteSplitter: not an encrypted file")
+
+// encWriter seals every Write into a frame of its own
+type encWriter struct {
+	w     io.WriteCloser
+	aead  cipher.AEAD
+	nonce []byte
+	n     uint32 // frames sealed so far
+	buf   []byte
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
+
+func newEncWriter(w io.WriteCloser, key []byte) (*encWriter, error) {
+	aead, e := newAEAD(key)
+	if e != nil {
+		return nil, e
+	}
+	ew := &encWriter{w: w, aead: aead, nonce: make([]byte, aead.NonceSize())}
+	if _, e = io.ReadFull(rand.Reader, ew.nonce[:encPrefix]); e != nil {
+		return nil, e
+	}
+	hdr := append([]byte(encMagic), ew.nonce[:encPrefix]...)
+	if _, e = w.Write(hdr); e != nil {
+		return nil, e
+	}
+	return ew, nil
+}
+
+func (ew *encWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > encMaxFrame {
+			chunk = chunk[:encMaxFrame]
+		}
+		if e := ew.seal(chunk, encMore); e != nil {
+			return n, e
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+// seal writes p to the file as the next frame
+func (ew *encWriter) seal(p, ad []byte) error {
+	if ew.n == ^uint32(0) {
+		return errors.New("WriteSplitter: too many frames for one encrypted file")
+	}
+	binary.BigEndian.PutUint32(ew.nonce[encPrefix:], ew.n)
+	ew.n++
+
+	ew.buf = append(ew.buf[:0], 0, 0, 0, 0)
+	ew.buf = ew.aead.Seal(ew.buf, ew.nonce, p, ad)
+	binary.BigEndian.PutUint32(ew.buf, uint32(len(ew.buf)-4))
+	_, e := ew.w.Write(ew.buf)
+	return e
+}
+
+// Sync commits the frames written so far to disk, if the file supports it
+func (ew *encWriter) Sync() error {
+	if f, ok := ew.w.(interface{ Sync() error }); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+// Close writes the final frame and closes the file
+func (ew *encWriter) Close() error {
+	e := ew.seal(nil, encLast)
+	if ce := ew.w.Close(); e == nil {
+		e = ce
+	}
+	return e
+}
+
+// encrypted reports whether files are encrypted, see Key
+func (ws *WriteSplitter) encrypted() bool {
+	return ws.Key != nil || ws.KeyFunc != nil
+}
+
+// encrypt wraps the newly created f, named filename, in an encWriter if Key
+// or KeyFunc is set. If that fails f is closed and removed.
+func (ws *WriteSplitter) encrypt(f io.WriteCloser, filename string) (io.WriteCloser, string, error) {
+	if !ws.encrypted() {
+		return f, filename, nil
+	}
+
+	key, e := ws.Key, error(nil)
+	if ws.KeyFunc != nil {
+		e = guard("KeyFunc", func() (e error) {
+			key, e = ws.KeyFunc(strings.TrimSuffix(filename, ws.TempSuffix))
+			return e
+		})
+	}
+	var ew *encWriter
+	if e == nil {
+		ew, e = newEncWriter(f, key)
+	}
+	if e != nil {
+		f.Close()
+		if ws.Factory == nil {
+			os.Remove(filename)
+		}
+		return nil, filename, e
+	}
+	return ew, filename, nil
+}
+
+// Decrypt returns a reader of the plaintext of an encrypted file read from r,
+// see Key. A file that was cut short, e.g. by a crash, reads up to the last
+// whole frame and then fails with ErrTruncated.
+func Decrypt(r io.Reader, key []byte) (io.Reader, error) {
+	aead, e := newAEAD(key)
+	if e != nil {
+		return nil, e
+	}
+	hdr := make([]byte, len(encMagic)+encPrefix)
+	if _, e = io.ReadFull(r, hdr); e != nil || string(hdr[:len(encMagic)]) != encMagic {
+		return nil, ErrNotEncrypted
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, hdr[len(encMagic):])
+	return &decReader{r: r, aead: aead, nonce: nonce}, nil
+}
+
+// decReader opens the frames written by an encWriter one at a time
+type decReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	nonce []byte
+	n     uint32
+	buf   []byte // ciphertext of the frame being read
+	plain []byte // what's left to read of the frame opened last
+	err   error
+}
+
+func (dr *decReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 && dr.err == nil {
+		dr.err = dr.next()
+	}
+	if len(dr.plain) == 0 {
+		return 0, dr.err
+	}
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
+
+// next opens the next frame, returning io.EOF after the final one
+func (dr *decReader) next() error {
+	var size [4]byte
+	if _, e := io.ReadFull(dr.r, size[:]); e != nil {
+		if e == io.EOF || e == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return e
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < uint32(dr.aead.Overhead()) || n > encMaxFrame+uint32(dr.aead.Overhead()) {
+		return errors.New("WriteSplitter: bad frame in encrypted file")
+	}
+	if cap(dr.buf) < int(n) {
+		dr.buf = make([]byte, n)
+	}
+	dr.buf = dr.buf[:n]
+	if _, e := io.ReadFull(dr.r, dr.buf); e != nil {
+		if e == io.EOF || e == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return e
+	}
+
+	binary.BigEndian.PutUint32(dr.nonce[encPrefix:], dr.n)
+	dr.n++
+	if n == uint32(dr.aead.Overhead()) { // empty, so it may be the last
+		if _, e := dr.aead.Open(nil, dr.nonce, dr.buf, encLast); e == nil {
+			return io.EOF
+		}
+	}
+	plain, e := dr.aead.Open(dr.buf[:0], dr.nonce, dr.buf, encMore)
+	if e != nil {
+		return e
+	}
+	dr.plain = plain
+	return nil
+}
@@ -0,0 +1,110 @@
+package writesplitter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+// decrypted returns the plaintext of the named encrypted file
+func decrypted(t *testing.T, name string) []byte {
+	t.Helper()
+	f, e := os.Open(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+	r, e := Decrypt(f, testKey)
+	if e != nil {
+		t.Fatal(e)
+	}
+	b, e := io.ReadAll(r)
+	if e != nil {
+		t.Fatalf("%s: %v", name, e)
+	}
+	return b
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(2, dir, "x")
+	ws.Key = testKey
+	ws.Buffer = 64
+	ws.DropCache = true
+	for i := 0; i < 5; i++ {
+		if _, e := ws.Write([]byte("secret\n")); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e := ws.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	var all []byte
+	for _, name := range ws.Files() {
+		raw, _ := os.ReadFile(name)
+		if bytes.Contains(raw, []byte("secret")) {
+			t.Errorf("%s holds plaintext", name)
+		}
+		all = append(all, decrypted(t, name)...)
+	}
+	if want := bytes.Repeat([]byte("secret\n"), 5); !bytes.Equal(all, want) {
+		t.Errorf("got %q, want %q", all, want)
+	}
+}
+
+func TestDecryptTruncated(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "x")
+	ws.Key = testKey
+	ws.Write([]byte("one\n"))
+	ws.Write([]byte("two\n"))
+	ws.Close()
+
+	raw, _ := os.ReadFile(ws.Files()[0])
+	r, e := Decrypt(bytes.NewReader(raw[:len(raw)-1]), testKey)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = io.ReadAll(r); e != ErrTruncated {
+		t.Errorf("got %v, want ErrTruncated", e)
+	}
+}
+
+func TestEncryptNeverResumes(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "x")
+	ws.Key = testKey
+	ws.Write([]byte("secret-one\n"))
+	ws.Close()
+
+	ws = LineSplitter(0, dir, "x")
+	ws.Key, ws.Resume = testKey, true
+	ws.Write([]byte("secret-two\n"))
+	ws.Close()
+
+	names, _ := filepath.Glob(filepath.Join(dir, "x*"))
+	if len(names) != 2 {
+		t.Fatalf("got %d files, want a new one for the second run", len(names))
+	}
+	for _, name := range names {
+		raw, _ := os.ReadFile(name)
+		if bytes.Contains(raw, []byte("secret")) {
+			t.Errorf("%s holds plaintext", name)
+		}
+		decrypted(t, name)
+	}
+}
+
+func TestEncryptRejectsSumSuffix(t *testing.T) {
+	ws := LineSplitter(0, t.TempDir(), "x")
+	ws.Key, ws.Hash, ws.SumSuffix = testKey, sha256.New, ".sha256"
+	if e := ws.Validate(); e == nil {
+		t.Error("SumSuffix with Key validated")
+	}
+}
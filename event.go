@@ -0,0 +1,58 @@
+package writesplitter
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventVersion is the version of the Event schema, bumped on any change that
+// isn't backwards compatible
+const EventVersion = 1
+
+// Event is a rotation of a WriteSplitter in a stable, versioned form for
+// consumers outside the process, see EventsTo
+type Event struct {
+	Version int       `json:"version"`
+	Kind    string    `json:"kind"` // "rotate"
+	Time    time.Time `json:"time"`
+	Old     string    `json:"old,omitempty"` // the file closed, see OnRotate
+	New     string    `json:"new"`           // the file created
+}
+
+// EventEncoder serializes Events, e.g. as protobuf
+type EventEncoder interface {
+	Encode(Event) ([]byte, error)
+}
+
+// JSONEncoder encodes Events as JSON, the default for EventsTo
+type JSONEncoder struct{}
+
+// Encode returns ev as a JSON object
+func (JSONEncoder) Encode(ev Event) ([]byte, error) {
+	return json.Marshal(ev)
+}
+
+// EventsTo encodes every rotation as an Event using enc, JSONEncoder if nil,
+// and hands it to send, e.g. to post it to a webhook or message bus. It is
+// done from OnRotate, after any OnRotate already set, so send must not call
+// ws. Errors from enc and send are passed to OnError.
+func (ws *WriteSplitter) EventsTo(enc EventEncoder, send func([]byte) error) {
+	if enc == nil {
+		enc = JSONEncoder{}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	prev := ws.OnRotate
+	ws.OnRotate = func(oldPath, newPath string) {
+		if prev != nil {
+			prev(oldPath, newPath)
+		}
+		b, e := enc.Encode(Event{EventVersion, "rotate", time.Now(), oldPath, newPath})
+		if e == nil {
+			e = send(b)
+		}
+		ws.report(e)
+	}
+}
@@ -0,0 +1,57 @@
+package writesplitter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// kindEncoder encodes only the Kind of an Event, to stand in for a custom
+// format
+type kindEncoder struct{}
+
+func (kindEncoder) Encode(ev Event) ([]byte, error) { return []byte(ev.Kind), nil }
+
+func TestEventsTo(t *testing.T) {
+	sendErr := errors.New("bus down")
+	tests := []struct {
+		name     string
+		enc      EventEncoder
+		sendErr  error
+		check    func([]byte) bool
+		reported error
+	}{
+		{"json by default", nil, nil, func(b []byte) bool {
+			var ev Event
+			return json.Unmarshal(b, &ev) == nil && ev.Version == EventVersion && ev.Kind == "rotate" && ev.New != "" && ev.Old != ""
+		}, nil},
+		{"custom encoder", kindEncoder{}, nil, func(b []byte) bool { return string(b) == "rotate" }, nil},
+		{"failing send", nil, sendErr, func([]byte) bool { return true }, sendErr},
+	}
+	for _, tt := range tests {
+		ws := LineSplitter(1, t.TempDir(), "x")
+		var rotations int
+		ws.OnRotate = func(_, _ string) { rotations++ }
+		var reported error
+		ws.OnError = func(e error) { reported = e }
+
+		var sent [][]byte
+		ws.EventsTo(tt.enc, func(b []byte) error {
+			sent = append(sent, b)
+			return tt.sendErr
+		})
+		ws.Write([]byte("a\n"))
+		ws.Write([]byte("b\n")) // rotates
+		ws.Close()
+
+		if len(sent) != 1 || !tt.check(sent[0]) {
+			t.Errorf("%s: sent %q", tt.name, sent)
+		}
+		if rotations != 1 {
+			t.Errorf("%s: the OnRotate already set was called %d times", tt.name, rotations)
+		}
+		if reported != tt.reported {
+			t.Errorf("%s: reported %v, want %v", tt.name, reported, tt.reported)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package writesplitter
+
+import (
+	"context"
+	"io"
+)
+
+// FileFactory creates the files a WriteSplitter writes to, so they can live
+// somewhere other than the local disk, e.g. in memory, behind an encrypting
+// writer or in an object store. name is the full name the WriteSplitter chose,
+// Dir included. Files are closed once rotated away from.
+type FileFactory interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// ContextFileFactory is a FileFactory that is also given the context of the
+// call that created the file, see WriteSplitter.WriteContext. It is used in
+// place of Create when a WriteSplitter's Factory implements it.
+type ContextFileFactory interface {
+	FileFactory
+	CreateContext(ctx context.Context, name string) (io.WriteCloser, error)
+}
+
+// FileFactoryFunc adapts an ordinary func to a FileFactory
+type FileFactoryFunc func(name string) (io.WriteCloser, error)
+
+// Create calls fn(name)
+func (fn FileFactoryFunc) Create(name string) (io.WriteCloser, error) {
+	return fn(name)
+}
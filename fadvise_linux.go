@@ -0,0 +1,35 @@
+//go:build linux && (amd64 || arm64)
+
+package writesplitter
+
+import (
+	"os"
+	"syscall"
+)
+
+// dropCached is dropCache for the named file. It is only advice, failing to
+// give it is harmless.
+func dropCached(name string) {
+	f, e := os.Open(name) // linux syncs files open for reading too
+	if e != nil {
+		return
+	}
+	dropCache(f)
+	f.Close()
+}
+
+const fadvDontNeed = 4 // POSIX_FADV_DONTNEED
+
+// dropCache advises the kernel that the cached pages of f won't be needed
+// again, see posix_fadvise(2). Dirty pages can't be dropped, so f is synced
+// first.
+func dropCache(f *os.File) error {
+	if e := f.Sync(); e != nil {
+		return e
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, fadvDontNeed, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build !linux || !(amd64 || arm64)
+
+package writesplitter
+
+// dropCached is a no-op where posix_fadvise isn't wired up
+func dropCached(name string) {}
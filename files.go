@@ -0,0 +1,171 @@
+package writesplitter
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileRecord is what a WriteSplitter remembers about a file it created
+type fileRecord struct {
+	name    string
+	created time.Time // when the file became the open file
+	first   time.Time // time of the first write that wrote anything
+	last    time.Time // time of the last write that wrote anything
+	size    int64     // bytes written
+	lines   int64     // lines written, see WriteSplitter.Newlines
+	digest  []byte    // see WriteSplitter.Hash, set once the file is closed
+	gzipped bool      // name ends in .gz and the file is, or is being, compressed
+	durable bool      // synced to disk in its final form, see Barrier
+
+	meta map[string]string // see WriteSplitter.SetFileMeta
+}
+
+// wrote records a write of n bytes holding the given number of lines
+func (rec *fileRecord) wrote(n, lines int) {
+	if n <= 0 {
+		return
+	}
+	rec.size += int64(n)
+	rec.lines += int64(lines)
+	now := time.Now()
+	if rec.first.IsZero() {
+		rec.first = now
+	}
+	rec.last = now
+}
+
+// Files returns the names of every file this WriteSplitter has created, in the
+// order they were created
+func (ws *WriteSplitter) Files() []string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	names := make([]string, 0, len(ws.files))
+	for _, rec := range ws.files {
+		names = append(names, rec.name)
+	}
+	return names
+}
+
+// forget drops the record of the named file, leaving the file as is
+func (ws *WriteSplitter) forget(name string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	for i := len(ws.files) - 1; i >= 0; i-- { // most likely the last one
+		if ws.files[i].name == name {
+			ws.files = append(ws.files[:i], ws.files[i+1:]...)
+			return
+		}
+	}
+}
+
+// Digest returns the digest computed by Hash for the named file. It is only
+// available once the file has been closed.
+func (ws *WriteSplitter) Digest(name string) ([]byte, bool) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	for _, rec := range ws.files {
+		if rec.name == name && rec.digest != nil {
+			return rec.digest, true
+		}
+	}
+	return nil, false
+}
+
+// FilesBetween returns the names of the files whose content was written at
+// some point between from and to, inclusive, in the order they were created.
+// Files that were never written to are left out.
+func (ws *WriteSplitter) FilesBetween(from, to time.Time) []string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var names []string
+	for _, rec := range ws.files {
+		if rec.first.IsZero() || rec.first.After(to) || rec.last.Before(from) {
+			continue
+		}
+		names = append(names, rec.name)
+	}
+	return names
+}
+
+// SetFileMeta attaches the key/value pair k, v to the open file, e.g. to link
+// it to the job or batch that wrote it. The metadata is in the FileInfo handed
+// to Header, Footer and Retention and in the State returned by Export. It
+// returns ErrNotAFile if no file is open.
+func (ws *WriteSplitter) SetFileMeta(k, v string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.current == "" {
+		return ErrNotAFile
+	}
+	rec := &ws.files[len(ws.files)-1]
+	if rec.meta == nil {
+		rec.meta = make(map[string]string)
+	}
+	rec.meta[k] = v
+	return nil
+}
+
+// CurrentFile returns the name of the open file, "" if there is none
+func (ws *WriteSplitter) CurrentFile() string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.current
+}
+
+// BytesWritten returns how many bytes have been written to the open file
+func (ws *WriteSplitter) BytesWritten() int64 {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return int64(ws.state.Bytes)
+}
+
+// LinesWritten returns how many lines have been written to the open file, as
+// counted toward Limit
+func (ws *WriteSplitter) LinesWritten() int64 {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return int64(ws.state.Lines)
+}
+
+// FilesCreated returns how many files this WriteSplitter has created,
+// including those since removed
+func (ws *WriteSplitter) FilesCreated() int {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.totalFiles
+}
+
+// TotalBytes returns how many bytes this WriteSplitter has written to all
+// of its files, not counting Header and Footer
+func (ws *WriteSplitter) TotalBytes() int64 {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.totalBytes
+}
+
+// writeSum writes the digest of rec to its sidecar, see SumSuffix, in the
+// format of sha256sum and friends, so the sidecar checks out against the file
+// on disk. For a compressed file that is a digest of the compressed file,
+// taken once it is; FileInfo.Digest stays that of the data as written.
+func (ws *WriteSplitter) writeSum(rec fileRecord) error {
+	if ws.SumSuffix == "" || rec.digest == nil {
+		return nil
+	}
+	sum := rec.digest
+	if rec.gzipped {
+		h := ws.Hash()
+		if e := hashFile(rec.name, h); e != nil {
+			return e
+		}
+		sum = h.Sum(nil)
+	}
+	line := hex.EncodeToString(sum) + "  " + filepath.Base(rec.name) + "\n"
+	return os.WriteFile(rec.name+ws.SumSuffix, []byte(line), ws.fileMode())
+}
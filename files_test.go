@@ -0,0 +1,58 @@
+package writesplitter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+func TestResumeDigest(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "x")
+	ws.Write([]byte("one\n"))
+	ws.Close()
+
+	ws = LineSplitter(0, dir, "x")
+	ws.Resume, ws.Hash, ws.SumSuffix = true, sha256.New, ".sha256"
+	ws.Write([]byte("two\n"))
+	ws.Close()
+
+	name := ws.Files()[0]
+	b, _ := os.ReadFile(name)
+	if string(b) != "one\ntwo\n" {
+		t.Fatalf("resumed file holds %q", b)
+	}
+	sum, e := os.ReadFile(name + ".sha256")
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := sha256.Sum256(b)
+	if !bytes.HasPrefix(sum, []byte(hex.EncodeToString(want[:]))) {
+		t.Errorf("sidecar %q doesn't match the file", sum)
+	}
+}
+
+func TestSumCompressed(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(1, dir, "x")
+	ws.Compress, ws.Hash, ws.SumSuffix = true, sha256.New, ".sha256"
+	ws.Write([]byte("one\n"))
+	ws.Write([]byte("two\n")) // the first file is compressed
+	ws.Close()
+
+	name := ws.Files()[0]
+	b, _ := os.ReadFile(name)
+	sum, e := os.ReadFile(name + ".sha256")
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := sha256.Sum256(b)
+	if !bytes.HasPrefix(sum, []byte(hex.EncodeToString(want[:]))) {
+		t.Errorf("sidecar %q doesn't match %s", sum, name)
+	}
+	if raw := sha256.Sum256([]byte("one\n")); !bytes.Equal(ws.files[0].digest, raw[:]) {
+		t.Error("the digest is no longer of the data as written")
+	}
+}
@@ -0,0 +1,60 @@
+package writesplitter
+
+import "time"
+
+// GroupRotation describes a rotation of every WriteSplitter in a Group, in the
+// order they were given to NewGroup
+type GroupRotation struct {
+	Time time.Time
+	Old  []string // the files closed, "" where there was none, see OnRotate
+	New  []string // the files created, "" where creating one failed
+}
+
+// Group rotates related WriteSplitters, e.g. an access, error and audit log,
+// together so their files cover the same span of time
+type Group struct {
+	OnRotate func(GroupRotation) // if set, called after each rotation of the Group as a whole
+
+	members []*WriteSplitter
+}
+
+// NewGroup returns a Group of members. A WriteSplitter must appear only once,
+// in only one Group.
+func NewGroup(members ...*WriteSplitter) *Group {
+	return &Group{members: members}
+}
+
+// Rotate rotates every member at the same instant: no member can be written
+// to until all of them have rotated. It returns the first error encountered
+// but rotates every member regardless. OnRotate is called before the members
+// are unlocked, so it must not call them.
+func (g *Group) Rotate() error {
+	for _, ws := range g.members {
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+	}
+
+	gr := GroupRotation{
+		Time: time.Now(),
+		Old:  make([]string, len(g.members)),
+		New:  make([]string, len(g.members)),
+	}
+	var err error
+	for i, ws := range g.members {
+		prev, e := ws.rotateFrom()
+		if e != nil && err == nil {
+			err = e
+		}
+		gr.Old[i], gr.New[i] = prev, ws.current
+	}
+
+	if g.OnRotate != nil {
+		if e := guard("OnRotate", func() error {
+			g.OnRotate(gr)
+			return nil
+		}); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
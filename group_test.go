@@ -0,0 +1,53 @@
+package writesplitter
+
+import "testing"
+
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		written []bool // which members have a file open before the rotation
+	}{
+		{"all open", []bool{true, true, true}},
+		{"one not yet written to", []bool{true, false, true}},
+		{"none open", []bool{false, false}},
+	}
+	for _, tt := range tests {
+		var members []*WriteSplitter
+		for _, written := range tt.written {
+			ws := LineSplitter(0, t.TempDir(), "x")
+			if written {
+				ws.Write([]byte("a\n"))
+			}
+			members = append(members, ws)
+		}
+
+		var got []GroupRotation
+		g := NewGroup(members...)
+		g.OnRotate = func(gr GroupRotation) { got = append(got, gr) }
+		if e := g.Rotate(); e != nil {
+			t.Errorf("%s: %v", tt.name, e)
+		}
+
+		if len(got) != 1 {
+			t.Fatalf("%s: OnRotate called %d times, want once", tt.name, len(got))
+		}
+		for i, ws := range members {
+			if old := got[0].Old[i]; (old != "") != tt.written[i] {
+				t.Errorf("%s: member %d closed %q", tt.name, i, old)
+			}
+			if got[0].New[i] == "" || got[0].New[i] != ws.CurrentFile() {
+				t.Errorf("%s: member %d created %q, has %q open", tt.name, i, got[0].New[i], ws.CurrentFile())
+			}
+			ws.Close()
+		}
+	}
+}
+
+func TestGroupOnRotatePanics(t *testing.T) {
+	g := NewGroup(LineSplitter(0, t.TempDir(), "x"))
+	g.OnRotate = func(GroupRotation) { panic("boom") }
+	if _, ok := g.Rotate().(*PanicError); !ok {
+		t.Error("the panic wasn't turned into a PanicError")
+	}
+	g.members[0].Close()
+}
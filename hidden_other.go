@@ -0,0 +1,14 @@
+//go:build !windows
+
+package writesplitter
+
+// Hidden returns ErrUnsupported outside of windows, where hiding a file is a
+// matter of its name
+func Hidden(name string) error {
+	return ErrUnsupported
+}
+
+// Archive returns ErrUnsupported outside of windows
+func Archive(name string) error {
+	return ErrUnsupported
+}
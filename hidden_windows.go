@@ -0,0 +1,28 @@
+//go:build windows
+
+package writesplitter
+
+import "syscall"
+
+// Hidden sets the hidden attribute on the named file
+func Hidden(name string) error {
+	return setAttr(name, syscall.FILE_ATTRIBUTE_HIDDEN)
+}
+
+// Archive sets the archive attribute on the named file, marking it for backup
+func Archive(name string) error {
+	return setAttr(name, syscall.FILE_ATTRIBUTE_ARCHIVE)
+}
+
+// setAttr adds attr to the attributes of the named file
+func setAttr(name string, attr uint32) error {
+	p, e := syscall.UTF16PtrFromString(name)
+	if e != nil {
+		return e
+	}
+	attrs, e := syscall.GetFileAttributes(p)
+	if e != nil {
+		return e
+	}
+	return syscall.SetFileAttributes(p, attrs|attr)
+}
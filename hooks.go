@@ -0,0 +1,35 @@
+package writesplitter
+
+import "fmt"
+
+// PanicError is what a panicking callback is turned into. It is returned by
+// the method that ran the callback or, where there is no error to return,
+// passed to OnError.
+type PanicError struct {
+	Hook  string      // the field holding the callback, e.g. "OnClosed"
+	Value interface{} // what the callback panicked with
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("WriteSplitter: %s panicked: %v", e.Hook, e.Value)
+}
+
+// guard runs fn, turning a panic into a *PanicError for hook
+func guard(hook string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{hook, v}
+		}
+	}()
+	return fn()
+}
+
+// report passes e to OnError, if set. A panic in OnError itself is dropped as
+// there is nowhere left to send it.
+func (ws *WriteSplitter) report(e error) {
+	if ws.OnError == nil || e == nil {
+		return
+	}
+	defer func() { recover() }()
+	ws.OnError(e)
+}
@@ -0,0 +1,89 @@
+package writesplitter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+func TestRotationPanics(t *testing.T) {
+	panicky := func(splitcore.State) bool { panic("boom") }
+	tests := []struct {
+		hook string
+		set  func(*WriteSplitter)
+	}{
+		{"Rotation", func(ws *WriteSplitter) { ws.Rotation = splitcore.RotationFunc(panicky) }},
+		{"NextRotation", func(ws *WriteSplitter) {
+			ws.NextRotation = func(time.Time) time.Time { panic("boom") }
+		}},
+		{"Factory", func(ws *WriteSplitter) {
+			ws.Factory = FileFactoryFunc(func(string) (io.WriteCloser, error) { panic("boom") })
+		}},
+	}
+	for _, tt := range tests {
+		ws := LineSplitter(0, t.TempDir(), "x")
+		tt.set(ws)
+		_, e := ws.Write([]byte("a"))
+		var pe *PanicError
+		if !errors.As(e, &pe) || pe.Hook != tt.hook {
+			t.Errorf("%s: got %v, want a PanicError", tt.hook, e)
+		}
+		ws.Close()
+	}
+}
+
+type ctxKey struct{}
+
+func TestWriteContextReachesHooks(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace")
+	var got []string
+	seen := func(hook string, ctx context.Context) {
+		if ctx.Value(ctxKey{}) == "trace" {
+			got = append(got, hook)
+		}
+	}
+
+	ws := LineSplitter(1, t.TempDir(), "x")
+	ws.OnRotateContext = func(ctx context.Context, _, _ string) { seen("OnRotateContext", ctx) }
+	ws.OnRotate = func(_, _ string) { got = append(got, "OnRotate") } // called as well, e.g. for EventsTo
+	ws.OnClosedContext = func(ctx context.Context, _ string, _ io.Reader) error {
+		seen("OnClosedContext", ctx)
+		return nil
+	}
+	ws.WriteContext(ctx, []byte("a\n"))
+	ws.WriteContext(ctx, []byte("b\n")) // rotates, closing the first file
+	ws.Close()
+
+	mem := LineSplitter(0, "", "x")
+	mem.Factory = ctxFactory(func(ctx context.Context, _ string) (io.WriteCloser, error) {
+		seen("CreateContext", ctx)
+		return nopCloser{io.Discard}, nil
+	})
+	mem.WriteContext(ctx, []byte("a\n"))
+	mem.Close()
+
+	want := []string{"OnClosedContext", "OnRotateContext", "OnRotate", "CreateContext"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// ctxFactory adapts a func to a ContextFileFactory
+type ctxFactory func(context.Context, string) (io.WriteCloser, error)
+
+func (fn ctxFactory) Create(name string) (io.WriteCloser, error) {
+	return fn(context.Background(), name)
+}
+
+func (fn ctxFactory) CreateContext(ctx context.Context, name string) (io.WriteCloser, error) {
+	return fn(ctx, name)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,37 @@
+//go:build linux && (amd64 || arm64)
+
+package writesplitter
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fsIocGetFlags = 0x80086601 // FS_IOC_GETFLAGS
+	fsIocSetFlags = 0x40086602 // FS_IOC_SETFLAGS
+	fsImmutableFl = 0x00000010 // FS_IMMUTABLE_FL
+)
+
+// Immutable sets the immutable flag on the named file, as chattr +i does, so
+// it can't be changed, renamed or removed, even by root, until the flag is
+// cleared, which goes for Retention and WarmDir too. It requires
+// CAP_LINUX_IMMUTABLE and a filesystem that supports it.
+func Immutable(name string) error {
+	f, e := os.Open(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+
+	var flags int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocGetFlags, uintptr(unsafe.Pointer(&flags))); errno != 0 {
+		return errno
+	}
+	flags |= fsImmutableFl
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocSetFlags, uintptr(unsafe.Pointer(&flags))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux || !(amd64 || arm64)
+
+package writesplitter
+
+// Immutable returns ErrUnsupported where the immutable flag isn't wired up
+func Immutable(name string) error {
+	return ErrUnsupported
+}
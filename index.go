@@ -0,0 +1,154 @@
+package writesplitter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+// IndexSuffix ends the name of the sidecar holding a file's index, see
+// IndexEvery
+const IndexSuffix = ".idx"
+
+// IndexEntry locates a record in a file: records are counted by Write, from
+// zero, and Offset is where the record starts in the file as written, before
+// any compression
+type IndexEntry struct {
+	Record int
+	Offset int64
+}
+
+// indexRecord adds the record about to be written to the index of the open
+// file if it's one of every IndexEvery
+func (ws *WriteSplitter) indexRecord() {
+	if ws.IndexEvery <= 0 || ws.current == "" {
+		return
+	}
+	if ws.idxRecs%ws.IndexEvery == 0 {
+		ws.idxBuf = fmt.Appendf(ws.idxBuf, "%d %d\n", ws.idxRecs, ws.idxOff)
+	}
+	ws.idxRecs++
+}
+
+// resumeIndex picks up the index of a file continued per Resume where it was
+// left: at the end of what was written, with the entries of its sidecar if
+// it has one. As with the line count, records are taken to be lines.
+func (ws *WriteSplitter) resumeIndex(name string, resumed splitcore.State) {
+	if ws.IndexEvery <= 0 || resumed.Bytes == 0 {
+		return
+	}
+	ws.idxOff, ws.idxRecs = int64(resumed.Bytes), resumed.Lines
+	idx, _ := ReadIndex(name) // without one, SeekRecord reads from the start
+	for _, ie := range idx {
+		ws.idxBuf = fmt.Appendf(ws.idxBuf, "%d %d\n", ie.Record, ie.Offset)
+	}
+}
+
+// writeIndex writes the index of the file just closed, now to be called name,
+// to its sidecar and resets it for the next file
+func (ws *WriteSplitter) writeIndex(name string) error {
+	defer func() {
+		ws.idxBuf, ws.idxRecs, ws.idxOff = ws.idxBuf[:0], 0, 0
+	}()
+	if ws.IndexEvery <= 0 || name == "" {
+		return nil
+	}
+	return os.WriteFile(name+IndexSuffix, ws.idxBuf, ws.fileMode())
+}
+
+// ReadIndex reads the index of the named file from its sidecar, see IndexEvery
+func ReadIndex(name string) ([]IndexEntry, error) {
+	f, e := os.Open(name + IndexSuffix)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+
+	var idx []IndexEntry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ie IndexEntry
+		if _, e := fmt.Sscanf(sc.Text(), "%d %d", &ie.Record, &ie.Offset); e != nil {
+			return nil, fmt.Errorf("WriteSplitter: bad index entry %q: %v", sc.Text(), e)
+		}
+		idx = append(idx, ie)
+	}
+	return idx, sc.Err()
+}
+
+// SeekRecord returns a RecordReader over the named file starting at the given
+// record, counted by Write from zero. It uses the file's index to skip most of
+// the file and reads through the rest, so it assumes each Write was a single
+// record as the RecordReader sees them, e.g. one line. Compressed files are
+// decompressed up to the indexed offset rather than seeked.
+func SeekRecord(name string, record int) (*RecordReader, error) {
+	idx, e := ReadIndex(name)
+	if e != nil {
+		return nil, e
+	}
+
+	i := sort.Search(len(idx), func(i int) bool { return idx[i].Record > record }) - 1
+	var start IndexEntry
+	if i >= 0 {
+		start = idx[i]
+	}
+
+	rr := NewRecordReader(name)
+	rr.sr.skip = start.Offset
+	for n := start.Record; n < record; n++ {
+		if _, e := rr.Next(); e != nil {
+			rr.Close()
+			return nil, e
+		}
+	}
+	return rr, nil
+}
+
+// skipTo advances r, reading from f, to offset: by seeking if r reads f as is,
+// otherwise by reading and discarding
+func skipTo(f *os.File, r io.Reader, offset int64) error {
+	if offset <= 0 {
+		return nil
+	}
+	if _, ok := r.(*gzip.Reader); !ok {
+		_, e := f.Seek(offset, io.SeekStart)
+		return e
+	}
+	_, e := io.CopyN(io.Discard, r, offset)
+	return e
+}
+
+// remove removes the named file and then its sidecars, returning the error
+// from removing the file itself
+func (ws *WriteSplitter) remove(name string) error {
+	e := os.Remove(name)
+	if e == nil || os.IsNotExist(e) {
+		for _, side := range ws.sidecars(name) {
+			os.Remove(side)
+		}
+	}
+	return e
+}
+
+// isSidecar reports whether name is that of a sidecar rather than a file
+func (ws *WriteSplitter) isSidecar(name string) bool {
+	return strings.HasSuffix(name, IndexSuffix) || (ws.SumSuffix != "" && strings.HasSuffix(name, ws.SumSuffix))
+}
+
+// sidecars returns the names of the files kept alongside the named one
+func (ws *WriteSplitter) sidecars(name string) []string {
+	var names []string
+	if ws.IndexEvery > 0 && !strings.HasSuffix(name, IndexSuffix) {
+		names = append(names, name+IndexSuffix)
+	}
+	if ws.Hash != nil && ws.SumSuffix != "" && !strings.HasSuffix(name, ws.SumSuffix) {
+		names = append(names, name+ws.SumSuffix)
+	}
+	return names
+}
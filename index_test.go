@@ -0,0 +1,35 @@
+package writesplitter
+
+import "testing"
+
+func TestResumeIndex(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "x")
+	ws.IndexEvery = 1
+	ws.Write([]byte("aaaaaaa\n"))
+	ws.Close()
+
+	ws = LineSplitter(0, dir, "x")
+	ws.Resume, ws.IndexEvery = true, 1
+	ws.Write([]byte("bbbbbbb\n"))
+	ws.Close()
+
+	name := ws.Files()[0]
+	idx, e := ReadIndex(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := []IndexEntry{{0, 0}, {1, 8}}
+	if len(idx) != len(want) || idx[0] != want[0] || idx[1] != want[1] {
+		t.Errorf("got index %v, want %v", idx, want)
+	}
+
+	rr, e := SeekRecord(name, 1)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer rr.Close()
+	if rec, _ := rr.Next(); string(rec) != "bbbbbbb" {
+		t.Errorf("record 1 is %q", rec)
+	}
+}
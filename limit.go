@@ -0,0 +1,87 @@
+package writesplitter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LimitError describes a limit ParseLimit couldn't make sense of
+type LimitError struct {
+	Input  string // the limit as given
+	Reason string
+}
+
+func (e *LimitError) Error() string {
+	return "WriteSplitter: invalid limit " + strconv.Quote(e.Input) + ": " + e.Reason
+}
+
+// multipliers are the size prefixes ParseLimit accepts, SI and IEC
+var multipliers = map[string]int64{
+	"":  1,
+	"k": 1e3, "m": 1e6, "g": 1e9, "t": 1e12,
+	"ki": 1 << 10, "mi": 1 << 20, "gi": 1 << 30, "ti": 1 << 40,
+}
+
+// ParseLimit parses a human friendly limit into a Limit and whether it counts
+// Bytes, e.g.
+//
+//	"100MB"      100,000,000 bytes
+//	"1GiB"       1,073,741,824 bytes
+//	"512 bytes"  512 bytes
+//	"50k lines"  50,000 lines
+//
+// A limit is a whole number, an optional multiplier (k, M, G, T or Ki, Mi, Gi,
+// Ti, in any case) and a unit: B or bytes for bytes, lines for lines. The unit
+// is required so that a bare number can't be mistaken for the wrong one. A
+// limit that can't be parsed is reported as a *LimitError.
+func ParseLimit(s string) (limit int, bytes bool, err error) {
+	in := strings.TrimSpace(s)
+	i := strings.IndexFunc(in, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i == 0 || in == "" {
+		return 0, false, &LimitError{s, "must start with a whole number"}
+	}
+	if i < 0 {
+		return 0, false, &LimitError{s, "missing a unit, e.g. B or lines"}
+	}
+
+	n, e := strconv.ParseInt(in[:i], 10, 64)
+	if e != nil {
+		return 0, false, &LimitError{s, "number out of range"}
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(in[i:]))
+	var prefix string
+	switch {
+	case strings.HasSuffix(unit, "lines"):
+		prefix = strings.TrimSpace(strings.TrimSuffix(unit, "lines"))
+	case strings.HasSuffix(unit, "bytes"):
+		prefix, bytes = strings.TrimSpace(strings.TrimSuffix(unit, "bytes")), true
+	case strings.HasSuffix(unit, "b"):
+		prefix, bytes = strings.TrimSuffix(unit, "b"), true
+	default:
+		return 0, false, &LimitError{s, "unknown unit " + strconv.Quote(in[i:]) + ", want B, bytes or lines"}
+	}
+
+	mult, ok := multipliers[prefix]
+	if !ok {
+		return 0, false, &LimitError{s, "unknown multiplier " + strconv.Quote(prefix)}
+	}
+	if n > int64(^uint(0)>>1)/mult {
+		return 0, false, &LimitError{s, "too large"}
+	}
+	return int(n * mult), bytes, nil
+}
+
+// SetLimit sets Limit and Bytes from a limit as understood by ParseLimit
+func (ws *WriteSplitter) SetLimit(s string) error {
+	limit, bytes, e := ParseLimit(s)
+	if e != nil {
+		return e
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.Limit, ws.Bytes = limit, bytes
+	return nil
+}
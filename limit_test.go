@@ -0,0 +1,85 @@
+package writesplitter
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in    string
+		limit int
+		bytes bool
+		ok    bool
+	}{
+		{"100MB", 100000000, true, true},
+		{"1GiB", 1 << 30, true, true},
+		{"512 bytes", 512, true, true},
+		{"512B", 512, true, true},
+		{"50k lines", 50000, false, true},
+		{" 10 Lines ", 10, false, true},
+		{"4 kib", 4096, true, true},
+		{"100", 0, false, false},        // no unit
+		{"MB", 0, false, false},         // no number
+		{"", 0, false, false},           // nothing
+		{"-5MB", 0, false, false},       // not a whole number
+		{"1.5GB", 0, false, false},      // nor is this
+		{"10 parsecs", 0, false, false}, // unknown unit
+		{"10XB", 0, false, false},       // unknown multiplier
+		{"99999999999999999999B", 0, false, false},
+		{"9999999999TiB", 0, false, false},
+	}
+	for _, tt := range tests {
+		limit, bytes, e := ParseLimit(tt.in)
+		if tt.ok {
+			if e != nil || limit != tt.limit || bytes != tt.bytes {
+				t.Errorf("ParseLimit(%q) = %d, %v, %v, want %d, %v", tt.in, limit, bytes, e, tt.limit, tt.bytes)
+			}
+			continue
+		}
+		if _, isLimitError := e.(*LimitError); !isLimitError {
+			t.Errorf("ParseLimit(%q): got %v, want a *LimitError", tt.in, e)
+		}
+	}
+}
+
+func TestPrecise(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		precise bool
+		align   bool
+		writes  []string
+		want    []string // contents of the files, in order
+	}{
+		{"whole writes", 4, false, false, []string{"abcdefgh"}, []string{"abcdefgh"}},
+		{"at the limit", 4, true, false, []string{"abcdefgh"}, []string{"abcd", "efgh"}},
+		{"across files", 4, true, false, []string{"abcdefghij"}, []string{"abcd", "efgh", "ij"}},
+		{"across writes", 4, true, false, []string{"abc", "def"}, []string{"abcd", "ef"}},
+		{"fits", 4, true, false, []string{"ab", "cd"}, []string{"abcd"}},
+		{"aligned", 6, true, true, []string{"ab\ncdefg\n"}, []string{"ab\n", "cdefg\n"}},
+		{"no newline to align to", 4, true, true, []string{"abcdefgh"}, []string{"abcd", "efgh"}},
+		{"newline past the limit", 4, true, true, []string{"abcdef\n"}, []string{"abcd", "ef\n"}},
+	}
+	for _, tt := range tests {
+		ws := ByteSplitter(tt.limit, t.TempDir(), "x")
+		ws.Precise = tt.precise
+		ws.AlignSplit = tt.align
+		for _, w := range tt.writes {
+			if n, e := ws.Write([]byte(w)); e != nil || n != len(w) {
+				t.Fatalf("%s: Write(%q) = %d, %v", tt.name, w, n, e)
+			}
+		}
+		ws.Close()
+
+		var got []string
+		for _, name := range ws.Files() {
+			b, _ := os.ReadFile(name)
+			got = append(got, string(b))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// relink points Link at filename, replacing the old link with a rename so it
+// never goes missing, even for an instant
+func (ws *WriteSplitter) relink(filename string) error {
+	if ws.Link == "" {
+		return nil
+	}
+
+	target := filename
+	if abs, e := filepath.Abs(filename); e == nil {
+		target = abs
+	}
+	if linkDir, e := filepath.Abs(filepath.Dir(ws.Link)); e == nil {
+		if rel, e := filepath.Rel(linkDir, target); e == nil {
+			target = rel // survives the whole tree being moved
+		}
+	}
+
+	tmp := ws.Link + ".tmp"
+	os.Remove(tmp) // left over from a crash
+	if e := os.Symlink(target, tmp); e != nil {
+		return e
+	}
+	if e := os.Rename(tmp, ws.Link); e != nil {
+		os.Remove(tmp)
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	tests := []struct {
+		name string
+		link func(dir string) string
+	}{
+		{"beside the files", func(dir string) string { return filepath.Join(dir, "current") }},
+		{"in another dir", func(dir string) string {
+			os.Mkdir(filepath.Join(dir, "links"), 0755)
+			return filepath.Join(dir, "links", "current")
+		}},
+		{"over a stale temp link", func(dir string) string {
+			link := filepath.Join(dir, "current")
+			os.Symlink("nowhere", link+".tmp")
+			return link
+		}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		ws := LineSplitter(1, dir, "x")
+		ws.Link = tt.link(dir)
+		for _, s := range []string{"a\n", "b\n", "c\n"} {
+			ws.Write([]byte(s))
+			b, e := os.ReadFile(ws.Link)
+			if e != nil || string(b) != s {
+				t.Errorf("%s: the link reads %q, %v, want %q", tt.name, b, e, s)
+			}
+		}
+		if target, _ := os.Readlink(ws.Link); filepath.IsAbs(target) {
+			t.Errorf("%s: the link points at %s, want a relative path", tt.name, target)
+		}
+		if _, e := os.Lstat(ws.Link + ".tmp"); !os.IsNotExist(e) {
+			t.Errorf("%s: the temporary link was left behind", tt.name)
+		}
+		ws.Close()
+	}
+}
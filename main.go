@@ -1,16 +1,32 @@
 package writesplitter
 
 import (
+	"bufio"
+	"context"
 	"errors"
+	"fmt"
+	"hash"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
 )
 
 // a custom error to signal that no file was closed
 var (
-	ErrNotAFile = errors.New("WriteSplitter: invalid memory address or nil pointer dereference")
-	ErrNotADir  = errors.New("WriteSplitter: specified dir is not a dir")
+	ErrNotAFile  = errors.New("WriteSplitter: invalid memory address or nil pointer dereference")
+	ErrNotADir   = errors.New("WriteSplitter: specified dir is not a dir")
+	ErrNoDefault = errors.New("WriteSplitter: no default WriteSplitter has been set")
+	ErrCanceled  = errors.New("WriteSplitter: context canceled")
+	ErrDirGone   = errors.New("WriteSplitter: dir no longer exists")
+	ErrInUse     = errors.New("WriteSplitter: state can only be imported before the first write")
+	ErrClosed    = errors.New("WriteSplitter: closed")
 )
 
 // WriteSplitter represents a disk bound io.WriteCloser that splits the input
@@ -21,16 +37,139 @@ var (
 // other words, if a []byte sent to `Write()` contains enough bytes or new
 // lines ('\n') to exceed the given limit, a new file won't be generated until
 // the *next* invocation of `Write()`. If both LineLimit and ByteLimit are set,
-// preference is given to LineLimit. By default, no splitting occurs because
-// both LineLimit and ByteLimit are zero (0).
+// preference is given to LineLimit. Independently of Limit, an Interval splits
+// files by age, checked the same way. By default, no splitting occurs because
+// both LineLimit and ByteLimit are zero (0) and there is no Interval.
+//
+// Dir may contain the tokens %Y, %m, %d, %H, %M and %S for the zero padded
+// date and time, %h for the host name and %% for a literal %, e.g.
+// "/var/log/app/%Y-%m-%d". They are expanded, and the dir created if need be,
+// every time a file is created.
+//
+// A WriteSplitter is safe for concurrent use. Its methods are serialized: a
+// Close or Rotate called during a Write waits for that Write to finish, and a
+// Rotate that races a rotation triggered by the limit simply happens after it.
+// Once closed, Write, Rotate and Barrier return ErrClosed rather than create a
+// file, until Reset. The exported fields are configuration and must not be changed once writing
+// has begun. Callbacks are run with the WriteSplitter locked and must not call
+// its methods.
 type WriteSplitter struct {
-	Limit    int      // how many write ops (typically one per line) before splitting the file
-	Dir      string   // files are named: $prefix + $nano-precision-timestamp + '.log'
-	Prefix   string   // files are named: $prefix + $nano-precision-timestamp + '.log'
-	Bytes    bool     // split by bytes and not lines
-	numBytes int      // internal byte count
-	numLines int      // internal line count
-	handle   *os.File // embedded file
+	Limit       int           // how many write ops (typically one per line) before splitting the file
+	Interval    time.Duration // how long a file is written to before splitting, checked on Write
+	Dir         string        // files are named: $prefix + $nano-precision-timestamp + '.log'
+	Prefix      string        // files are named: $prefix + $nano-precision-timestamp + '.log'
+	Template    string        // if set, a text/template for filenames used instead of the above, see NameData
+	Bytes       bool          // split by bytes and not lines
+	Newlines    bool          // count the '\n' in each write as lines, rather than one line per write
+	Ephemeral   bool          // remove every created file on Close, see Cleanup
+	PreOpen     bool          // create the next file just before the limit so rotation is a swap
+	Async       bool          // finalize rotated files in the background, in creation order, off the Write path
+	RemoveEmpty bool          // remove files that were closed without anything written to them
+	PerWrite    bool          // every Write gets a file of its own that is closed before Write returns
+	AppendOnly  bool          // open files with O_APPEND, so every write lands at the end
+	DropCache   bool          // once closed, advise the kernel to drop the file from the page cache (linux only)
+	Strict      bool          // validate the configuration before the first file is created, see Validate
+	Compress    bool          // gzip rotated files in the background, removing the originals
+	KeepRaw     bool          // with Compress, keep the originals too, see RawRetention
+	SizeInName  bool          // once closed, rename files to end in their line and byte count, e.g. _100L_5120B
+	Link        string        // if set, a symlink kept pointing at the open file, e.g. for tail -F
+	Buffer      int           // if set, writes are buffered in memory up to this many bytes, see Flush
+	FlushEvery  time.Duration // with Buffer, how long written data may sit in the buffer
+	Resume      bool          // continue the most recent file in Dir named as ws names them rather than create a new one at first, unless encrypting, writing a Footer or a Sequence
+	Sequence    bool          // prefix each Write with its sequence number and a space, e.g. "42 ", counting across files, and across restarts by Export and Import
+	FileMode    os.FileMode   // permissions of new files, before the umask, 0666 if zero
+	DirMode     os.FileMode   // permissions of new dirs, before the umask, 0755 if zero
+	MkdirAll    bool          // create Dir, and any parents, if it doesn't exist, as RecreateMissingDir does
+	Flags       int           // if set, files are opened O_WRONLY|Flags, not O_CREATE|O_EXCL, e.g. to append to shared files
+	SumSuffix   string        // with Hash, each file's digest is written to a sidecar named with it appended, e.g. ".sha256"
+	IndexEvery  int           // if set, the offset of every this many records is kept in a sidecar, see SeekRecord
+	TempSuffix  string        // if set, e.g. ".tmp", the open file's name ends in it until the file is complete
+	Sortable    bool          // name files so they sort by creation in any locale, see splitcore.AppendSortableName
+	Precise     bool          // with Bytes, split a Write across files rather than let a file exceed Limit
+	AlignSplit  bool          // with Precise, split after the last '\n' that fits, where there is one
+
+	Context   context.Context                      // if set, writes fail with ErrCanceled once it is done
+	OnCreate  func(*os.File) error                 // if set, called on each new file, e.g. to chattr +a it; an error discards the file
+	OnClosed  func(name string, r io.Reader) error // if set, handed every completed file to read, e.g. to upload it
+	RouteKey  func([]byte) string                  // if set, each write goes to the Stream named by its result, or ws itself for ""
+	OnRotate  func(oldPath, newPath string)        // if set, called after each rotation, see rotateFrom; newPath is "" with PerWrite
+	OnError   func(error)                          // if set, given errors from callbacks and background work, possibly from another goroutine
+	Hash      func() hash.Hash                     // if set, a digest of each file is computed as it is written, see Digest
+	Header    func(FileInfo) []byte                // if set, its result is written at the start of every file
+	Footer    func(FileInfo) []byte                // if set, its result is written at the end of every file
+	Retention RetentionPolicy                      // if set, consulted on every rotation for completed files to delete, those of earlier runs included, see adopt
+	Rotation  splitcore.RotationPolicy             // if set, decides when to rotate in place of Limit and Interval
+	Factory   FileFactory                          // if set, creates files in place of the local disk, see FileFactory
+
+	OnFinalized  func(name string) error // if set, called last on each completed file, e.g. with Attrs(ReadOnly, Immutable)
+	RotateBefore func([]byte) bool       // if set, a Write it returns true for starts a new file, e.g. regexp.MustCompile(`^BEGIN`).Match
+	RawRetention RetentionPolicy         // with KeepRaw, consulted on every rotation for raw copies to delete
+
+	SoftLimit   float64                  // fraction of Limit, e.g. 0.8, at which OnSoftLimit is called
+	OnSoftLimit func(name string, n int) // called once per file with the line or byte count that crossed SoftLimit
+
+	MissingDir  MissingDirPolicy // what to do if Dir disappears while in use
+	FallbackDir string           // see FallbackMissingDir
+
+	WarmDir   string        // completed files are moved here per WarmAfter and HotBytes, see tier
+	WarmAfter time.Duration // move completed files older than this to WarmDir
+	HotBytes  int64         // move the oldest completed files to WarmDir while Dir holds more than this
+
+	MaxTotalBytes int64 // if set, writes fail with ErrQuotaExceeded rather than take what's on disk past this many bytes, see diskQuota
+	PruneToQuota  bool  // with MaxTotalBytes, delete the oldest completed files to make room rather than fail
+
+	Key     []byte                            // if set, an AES key of 16, 24 or 32 bytes every file is encrypted with, see Decrypt
+	KeyFunc func(name string) ([]byte, error) // if set, returns the key for each new file in place of Key
+
+	NextRotation func(opened time.Time) time.Time // if set, returns when a file opened at opened is due to rotate, in place of Interval, e.g. splitcore.Aligned(time.Hour)
+
+	OnRotateContext func(ctx context.Context, oldPath, newPath string)        // if set, called before OnRotate with the context of the call that rotated, see WriteContext
+	OnClosedContext func(ctx context.Context, name string, r io.Reader) error // if set, called in place of OnClosed with the context of the call that completed the file
+
+	ShouldCompress func(FileInfo) bool // with Compress, if set, decides for each rotated file whether to compress it, e.g. to leave small files be
+
+	state    splitcore.State    // internal line and byte count
+	handle   io.WriteCloser     // embedded file
+	nextDir  string             // dir to use at the next rotation, see SetDir
+	files    []fileRecord       // every file created, in order
+	current  string             // name of the open file, empty once closed
+	tempDir  string             // dir made by NewTemp, removed by an ephemeral Close
+	next     io.WriteCloser     // file opened ahead of time, see PreOpen
+	nextName string             // name of next
+	hasher   hash.Hash          // digest of the open file, see Hash
+	nameBuf  []byte             // scratch space for building filenames
+	seq      int                // files opened so far, see NameData
+	tmpl     *template.Template // Template, parsed
+	softHit  bool               // OnSoftLimit has been called for the open file
+	barrier  bool               // files closed now are synced once final, see Barrier
+	quotaOf  *diskQuota         // see MaxTotalBytes, shared with streams
+	closed   bool               // set by Close, cleared by Reset
+	ctx      context.Context    // of the call in progress, see WriteContext
+
+	streams map[string]*WriteSplitter // see Stream
+
+	buf        *bufio.Writer // buffers writes to handle, see Buffer
+	flushTimer *time.Timer   // pending autoFlush
+
+	totalFiles int   // files created, see FilesCreated
+	totalBytes int64 // bytes written to all of them, see TotalBytes
+
+	records uint64 // sequence number of the last record written, see Sequence
+	seqBuf  []byte // scratch space for framing records
+
+	raws []rawCopy // raw copies of compressed files, see KeepRaw
+
+	idxBuf  []byte // index of the open file, see IndexEvery
+	idxRecs int    // records written to the open file
+	idxOff  int64  // bytes written to the open file, boundary records included
+
+	mu sync.Mutex // serializes every exported method
+
+	finalWG    sync.WaitGroup
+	finalMu    sync.Mutex
+	finalErr   error           // first error from a background finalize
+	finalizing map[string]bool // files being finalized in the background
+	finalLast  chan struct{}   // closed once the most recent background finalize is done
 }
 
 // LineSplitter returns a WriteSplitter set to split at the given number of lines
@@ -52,50 +191,739 @@ func ByteSplitter(limit int, dir, prefix string) *WriteSplitter {
 	}
 }
 
-// Close is a passthru and satisfies io.Closer. Subsequent writes will return an
-// error.
+// TimeSplitter returns a WriteSplitter set to split once a file has been
+// written to for the given interval
+func TimeSplitter(interval time.Duration, dir, prefix string) *WriteSplitter {
+	return &WriteSplitter{
+		Interval: interval,
+		Dir:      filepath.Clean(dir),
+		Prefix:   filepath.Clean(prefix),
+	}
+}
+
+// SpoolSplitter returns a WriteSplitter that writes each Write to a file of its
+// own, e.g. for spooling individual messages to disk. Each file is complete by
+// the time Write returns.
+func SpoolSplitter(dir, prefix string) *WriteSplitter {
+	return &WriteSplitter{
+		PerWrite: true,
+		Dir:      filepath.Clean(dir),
+		Prefix:   filepath.Clean(prefix),
+	}
+}
+
+// NewTemp returns an Ephemeral WriteSplitter whose files are created in a new
+// temporary dir, see os.MkdirTemp for how pattern is used. The dir is returned
+// alongside and is removed, with its files, on Close. opts are applied and the
+// result validated as by New; without any, Limit and Bytes are left at zero
+// and must be set before the first Write for splitting to occur.
+func NewTemp(pattern string, opts ...Option) (*WriteSplitter, string, error) {
+	dir, e := os.MkdirTemp("", pattern)
+	if e != nil {
+		return nil, "", e
+	}
+	ws := &WriteSplitter{
+		Dir:       dir,
+		Ephemeral: true,
+		tempDir:   dir,
+	}
+	if e := ws.apply(opts); e != nil {
+		os.Remove(dir)
+		return nil, "", e
+	}
+	return ws, dir, nil
+}
+
+// NewWithContext returns a copy of config tied to ctx: once ctx is done,
+// writes fail with ErrCanceled and the WriteSplitter is closed, flushing
+// Buffer and waiting for background finalizes, so nothing is left half done
+// at shutdown. The configuration is validated first. Errors from the closing
+// are passed to OnError.
+func NewWithContext(ctx context.Context, config *WriteSplitter) (*WriteSplitter, error) {
+	config.mu.Lock()
+	ws := config.clone()
+	config.mu.Unlock()
+
+	ws.Context = ctx
+	if e := ws.Validate(); e != nil {
+		return nil, e
+	}
+
+	go func() {
+		<-ctx.Done()
+		if e := ws.Close(); e != ErrNotAFile { // nothing written isn't worth reporting
+			ws.report(e)
+		}
+	}()
+	return ws, nil
+}
+
+// Close is a passthru and satisfies io.Closer. Subsequent writes will return
+// ErrClosed, until Reset. If Ephemeral is set, every file created is removed
+// after closing.
 func (ws *WriteSplitter) Close() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.closed = true
+	e := ws.closeFile(false)
+	if e == ErrNotAFile && (ws.PerWrite || len(ws.streams) > 0) { // nothing to close isn't an error here
+		e = nil
+	}
+	ws.discardNext()
+	if fe := ws.wait(); e == nil {
+		e = fe
+	}
+	if se := ws.closeStreams(); e == nil {
+		e = se
+	}
+	if ws.Ephemeral {
+		if ce := ws.cleanup(); e == nil {
+			e = ce
+		}
+		if ws.tempDir != "" {
+			if re := os.Remove(ws.tempDir); re != nil && !os.IsNotExist(re) && e == nil {
+				e = re
+			}
+		}
+	}
+	return e
+}
+
+// Cleanup removes every file this WriteSplitter has created except the one
+// still open for writing. It keeps going on failure and returns the first
+// error encountered.
+func (ws *WriteSplitter) Cleanup() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.cleanup()
+}
+
+// cleanup is Cleanup without the locking
+func (ws *WriteSplitter) cleanup() error {
+	var err error
+	var keep []fileRecord
+	for _, rec := range ws.files {
+		if !ws.finalized(rec.name) { // never pull a file out from under Write or finalize
+			keep = append(keep, rec)
+			continue
+		}
+		if e := ws.remove(rec.name); e != nil && !os.IsNotExist(e) && err == nil {
+			err = e
+		}
+	}
+	ws.files = keep
+
+	var keepRaw []rawCopy
+	for _, raw := range ws.raws {
+		if !ws.finalized(raw.of) {
+			keepRaw = append(keepRaw, raw)
+			continue
+		}
+		if e := os.Remove(raw.rec.name); e != nil && !os.IsNotExist(e) && err == nil {
+			err = e
+		}
+	}
+	ws.raws = keepRaw
+	return err
+}
+
+// Reset closes the current file and streams, if any, and clears the internal
+// state, counters included, so the WriteSplitter can be reused with its
+// current configuration, even once closed. Files already created are left on
+// disk, as is the dir made by NewTemp. It returns the first error from closing.
+func (ws *WriteSplitter) Reset() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	e := ws.closeFile(false)
+	if e == ErrNotAFile {
+		e = nil
+	}
+	ws.discardNext()
+	if fe := ws.wait(); e == nil {
+		e = fe
+	}
+	if se := ws.closeStreams(); e == nil {
+		e = se
+	}
+
+	ws.state = splitcore.State{}
+	ws.handle = nil
+	ws.nextDir = ""
+	ws.files = nil
+	ws.current = ""
+	ws.tempDir = ""
+	ws.hasher = nil
+	ws.seq = 0
+	ws.softHit = false
+	ws.streams = nil
+	ws.totalFiles, ws.totalBytes = 0, 0
+	ws.records = 0
+	ws.raws = nil
+	ws.closed = false
+	return e
+}
+
+// closeFile closes the current file without any of the bookkeeping of Close.
+// rotating is true when another file follows, in which case the file may be
+// compressed and finalized in the background, see wait. Background finalizes
+// run one at a time in the order the files were created.
+func (ws *WriteSplitter) closeFile(rotating bool) error {
+	async := rotating && (ws.Async || ws.Compress)
 	if ws.handle != nil { // do not try to close nil
-		ws.numLines, ws.numBytes = 0, 0
-		return ws.handle.Close()
+		f, durable, ctx := ws.handle, ws.barrier, ws.context()
+		var from string
+		var rec fileRecord
+		if ws.current != "" { // the open file is always the last one created
+			ws.report(ws.mark("Footer", ws.Footer))
+			last := &ws.files[len(ws.files)-1]
+			if ws.hasher != nil {
+				last.digest = ws.hasher.Sum(nil)
+				ws.hasher = nil
+			}
+			from = last.name
+			last.name = strings.TrimSuffix(last.name, ws.TempSuffix)
+			if ws.SizeInName {
+				last.name = fmt.Sprintf("%s_%dL_%dB", last.name, last.lines, last.size)
+			}
+			if rotating && ws.Compress && !(ws.RemoveEmpty && last.size == 0) && ws.shouldCompress(*last) {
+				if ws.KeepRaw {
+					raw := *last
+					raw.meta = copyMeta(last.meta)
+					ws.raws = append(ws.raws, rawCopy{raw, last.name + ".gz"})
+				}
+				last.name += ".gz"
+				last.gzipped = true
+			}
+			rec = *last
+			if ws.RemoveEmpty && rec.size == 0 {
+				ws.files = ws.files[:len(ws.files)-1]
+				ws.writeIndex("")
+			} else {
+				ws.report(ws.writeIndex(rec.name))
+				if !rec.gzipped { // there is nothing on disk to digest yet, see finalize
+					ws.report(ws.writeSum(rec))
+				}
+			}
+		}
+		fe := ws.flush()
+		ws.stopFlush()
+		ws.state = splitcore.State{}
+		ws.softHit = false
+		ws.current = ""
+		ws.handle = nil
+		if ws.buf != nil {
+			ws.buf.Reset(nil) // kept for the next file, but let go of this one
+		}
+		if async {
+			ws.finalWG.Add(1)
+			ws.finalMu.Lock()
+			if ws.finalizing == nil {
+				ws.finalizing = make(map[string]bool)
+			}
+			ws.finalizing[rec.name] = true
+			prev, done := ws.finalLast, make(chan struct{})
+			ws.finalLast = done
+			ws.finalMu.Unlock()
+			go func() {
+				defer ws.finalWG.Done()
+				defer close(done)
+				if prev != nil { // finalize in the order the files were created
+					<-prev
+				}
+				e := ws.finalize(ctx, f, from, rec, durable)
+				ws.report(e)
+				ws.finalMu.Lock()
+				delete(ws.finalizing, rec.name)
+				if e != nil && ws.finalErr == nil {
+					ws.finalErr = e
+				}
+				ws.finalMu.Unlock()
+			}()
+			return fe
+		}
+		if prev := ws.lastFinal(); prev != nil { // still finalize in the order the files were created
+			<-prev
+		}
+		if e := ws.finalize(ctx, f, from, rec, durable); e != nil {
+			return e
+		}
+		return fe
 	}
 	return ErrNotAFile // do not hide errors, but signal it's a WriteSplit error as opposed to an underlying os.* error
 }
 
+// shouldCompress asks ShouldCompress, if set, whether to compress the file
+// rec, compressing it if ShouldCompress panics
+func (ws *WriteSplitter) shouldCompress(rec fileRecord) bool {
+	if ws.ShouldCompress == nil {
+		return true
+	}
+	compress := true
+	ws.report(guard("ShouldCompress", func() error {
+		compress = ws.ShouldCompress(rec.info())
+		return nil
+	}))
+	return compress
+}
+
+// finalize does all the work needed once a file will no longer be written to.
+// from is the name the file was written under, rec.name its final name, and
+// ctx that of the call that completed it, see WriteContext. If durable is
+// set the file is synced to disk once in its final form, see Barrier.
+func (ws *WriteSplitter) finalize(ctx context.Context, f io.WriteCloser, from string, rec fileRecord, durable bool) error {
+	defer ws.release(from)
+	e := f.Close()
+	if e != nil || from == "" || ws.Factory != nil { // the rest needs the file on disk
+		return e
+	}
+	if ws.RemoveEmpty && rec.size == 0 {
+		return os.Remove(from)
+	}
+	if raw := strings.TrimSuffix(rec.name, ".gz"); rec.gzipped {
+		e = gzipFile(from, rec.name, ws.fileMode(), ws.KeepRaw)
+		if e == nil && ws.KeepRaw && from != raw {
+			e = os.Rename(from, raw)
+		}
+		if e == nil {
+			e = ws.writeSum(rec)
+		}
+	} else if from != rec.name {
+		e = os.Rename(from, rec.name)
+	}
+	if e == nil && durable {
+		e = ws.syncFinal(rec)
+	}
+	if e != nil {
+		return e
+	}
+	if ws.DropCache { // once compressed, encrypted and all, so nothing is read back in
+		dropCached(rec.name)
+		if rec.gzipped && ws.KeepRaw {
+			dropCached(strings.TrimSuffix(rec.name, ".gz"))
+		}
+	}
+	if ws.OnClosed != nil || ws.OnClosedContext != nil {
+		e = ws.consume(ctx, rec.name)
+	}
+	if e == nil && ws.OnFinalized != nil {
+		e = guard("OnFinalized", func() error { return ws.OnFinalized(rec.name) })
+	}
+	return e
+}
+
+// consume hands the completed file name to OnClosed, or OnClosedContext
+func (ws *WriteSplitter) consume(ctx context.Context, name string) error {
+	f, e := os.Open(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	if ws.OnClosedContext != nil {
+		return guard("OnClosedContext", func() error { return ws.OnClosedContext(ctx, name, f) })
+	}
+	return guard("OnClosed", func() error { return ws.OnClosed(name, f) })
+}
+
+// finalized reports whether name is done with, meaning it is neither open
+// nor being finalized in the background
+func (ws *WriteSplitter) finalized(name string) bool {
+	ws.finalMu.Lock()
+	defer ws.finalMu.Unlock()
+	return name != ws.current && !ws.finalizing[name]
+}
+
+// wait blocks until every background finalize is done and returns, then
+// clears, the first error any of them encountered
+func (ws *WriteSplitter) wait() error {
+	ws.finalWG.Wait()
+	ws.finalMu.Lock()
+	defer ws.finalMu.Unlock()
+	e := ws.finalErr
+	ws.finalErr = nil
+	return e
+}
+
 // Write satisfies io.Writer and internally manages file io. Write also limits
 // each WriteSplitter to only one open file at a time.
 func (ws *WriteSplitter) Write(p []byte) (int, error) {
+	return ws.write(context.Background(), p)
+}
+
+// WriteContext is Write, except that it returns ctx.Err() without writing if
+// ctx is already done, and ctx is handed on to whatever the Write sets off:
+// OnRotateContext, OnClosedContext and a ContextFileFactory. ctx is passed
+// for its values, e.g. trace and tenant IDs; OnClosedContext may run in the
+// background well after ctx is done.
+func (ws *WriteSplitter) WriteContext(ctx context.Context, p []byte) (int, error) {
+	if e := ctx.Err(); e != nil {
+		return 0, e
+	}
+	return ws.write(ctx, p)
+}
+
+// context returns the context of the call in progress, see WriteContext
+func (ws *WriteSplitter) context() context.Context {
+	if ws.ctx == nil {
+		return context.Background()
+	}
+	return ws.ctx
+}
+
+// write is Write, run with the context of its caller
+func (ws *WriteSplitter) write(ctx context.Context, p []byte) (int, error) {
+	if ws.RouteKey != nil {
+		var key string
+		if e := guard("RouteKey", func() error {
+			key = ws.RouteKey(p)
+			return nil
+		}); e != nil {
+			return 0, e
+		}
+		if key != "" { // keys come from the data, keep them out of other dirs
+			key = strings.NewReplacer("/", "_", `\`, "_").Replace(key)
+			return ws.stream(key).write(ctx, p)
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.ctx = ctx
+	defer func() { ws.ctx = nil }()
+
+	if ws.Context != nil && ws.Context.Err() != nil {
+		return 0, ErrCanceled
+	}
+	if ws.closed {
+		return 0, ErrClosed
+	}
 
 	var n int
 	var e error
 
 	if ws.handle == nil {
+		if ws.Strict && len(ws.files) == 0 {
+			if e = ws.Validate(); e != nil {
+				return 0, e
+			}
+		}
 		e = ws.create()
 	}
 
-	switch {
-	case ws.Limit > 0 && ws.Bytes && ws.numBytes >= ws.Limit:
-		fallthrough
-	case ws.Limit > 0 && ws.numLines >= ws.Limit:
-		ws.Close()
-		e = ws.create()
+	if due, ge := ws.due(); ge != nil {
+		e = ge
+	} else if due {
+		e = ws.rotate()
+	}
+
+	if e == nil && ws.RotateBefore != nil && ws.state.Bytes > 0 { // a fresh file is already a boundary
+		var match bool
+		e = guard("RotateBefore", func() error {
+			match = ws.RotateBefore(p)
+			return nil
+		})
+		if e == nil && match {
+			e = ws.rotate()
+		}
 	}
 
 	if e != nil {
 		return 0, e
 	}
 
-	n, e = ws.handle.Write(p)
-	ws.numLines += 1
-	ws.numBytes += n
+	if e = ws.quota(len(p)); e != nil {
+		return 0, e
+	}
+
+	ws.indexRecord()
+
+	var framed int // bytes of p that are its sequence number, see Sequence
+	if ws.Sequence {
+		ws.seqBuf = strconv.AppendUint(ws.seqBuf[:0], ws.records+1, 10)
+		ws.seqBuf = append(ws.seqBuf, ' ')
+		framed = len(ws.seqBuf)
+		ws.seqBuf = append(ws.seqBuf, p...)
+		p = ws.seqBuf
+	}
+
+	for policy := ws.policy(); ws.Precise && e == nil; {
+		k := policy.Cut(ws.state, p[n:], ws.AlignSplit)
+		if k == len(p)-n {
+			break
+		}
+		var m int
+		m, e = ws.put(p[n : n+k])
+		n += m
+		if e == nil {
+			e = ws.rotate()
+		}
+	}
+
+	if e == nil {
+		var m int
+		m, e = ws.put(p[n:])
+		n += m
+	}
+
+	if framed > 0 {
+		if n > 0 {
+			ws.records++
+		}
+		if n -= framed; n < 0 { // report only on the caller's bytes
+			n = 0
+		}
+	}
+
+	if !ws.softHit && ws.OnSoftLimit != nil && ws.SoftLimit > 0 && ws.Limit > 0 {
+		if policy := ws.policy(); policy.Reached(ws.state, ws.SoftLimit) {
+			count := policy.Count(ws.state)
+			ws.softHit = true
+			ws.report(guard("OnSoftLimit", func() error {
+				ws.OnSoftLimit(ws.current, count)
+				return nil
+			}))
+		}
+	}
+
+	if ws.PerWrite { // the next Write creates the next file, so this is the rotation
+		had, count := ws.current != "", len(ws.files)
+		ce := ws.closeFile(true)
+		if e == nil {
+			e = ce
+		}
+		if had && len(ws.files) == count {
+			ws.rotated(ws.files[count-1].name, "")
+		}
+		ws.prune()
+		return n, e
+	}
+
+	if ws.PreOpen && ws.next == nil && ws.policy().Reached(ws.state, 0.9) {
+		ws.next, ws.nextName, _ = ws.open() // on failure, create will try again at rotation
+		ws.hold(ws.nextName)
+	}
+	return n, e
+}
+
+// ReadFrom copies r into ws until EOF, so io.Copy(ws, r) can pipe a stream
+// such as stdin straight into it. When splitting by bytes, r is written in
+// chunks of up to 32KiB. Otherwise it is written a line at a time so Limit
+// counts its lines; lines longer than 32KiB are written in pieces that each
+// count as a line unless Newlines is set.
+func (ws *WriteSplitter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	if ws.Bytes {
+		buf := make([]byte, 32*1024)
+		for {
+			m, re := r.Read(buf)
+			if m > 0 {
+				n, e := ws.Write(buf[:m])
+				total += int64(n)
+				if e != nil {
+					return total, e
+				}
+			}
+			if re == io.EOF {
+				return total, nil
+			}
+			if re != nil {
+				return total, re
+			}
+		}
+	}
+
+	br := bufio.NewReaderSize(r, 32*1024)
+	for {
+		line, re := br.ReadSlice('\n')
+		if len(line) > 0 {
+			n, e := ws.Write(line)
+			total += int64(n)
+			if e != nil {
+				return total, e
+			}
+		}
+		switch {
+		case re == io.EOF:
+			return total, nil
+		case re != nil && re != bufio.ErrBufferFull:
+			return total, re
+		}
+	}
+}
+
+// writeAll writes p to w, retrying the remainder after a short write, and
+// returns the number of bytes actually written
+func writeAll(w io.Writer, p []byte) (int, error) {
+	var n int
+	for n < len(p) {
+		m, e := w.Write(p[n:])
+		n += m
+		switch {
+		case e == io.ErrShortWrite && m > 0: // progress was made, try the rest
+		case e != nil:
+			return n, e
+		case m == 0:
+			return n, io.ErrShortWrite
+		}
+	}
+	return n, nil
+}
+
+// put writes p to the open file and accounts for it
+func (ws *WriteSplitter) put(p []byte) (int, error) {
+	n, e := writeAll(ws.out(), p)
+	ws.autoFlush()
+	ws.idxOff += int64(n)
+	lines := ws.policy().Lines(p[:n])
+	ws.state.Add(n, lines)
+	ws.totalBytes += int64(n)
+	if ws.current != "" { // the open file is always the last one created
+		ws.files[len(ws.files)-1].wrote(n, lines)
+		if ws.hasher != nil {
+			ws.hasher.Write(p[:n])
+		}
+	}
 	return n, e
 }
 
+// policy returns the splitting configuration for splitcore
+func (ws *WriteSplitter) policy() splitcore.Policy {
+	return splitcore.Policy{
+		Limit:    ws.Limit,
+		Bytes:    ws.Bytes,
+		Newlines: ws.Newlines,
+		Interval: ws.Interval,
+		Next:     ws.NextRotation,
+	}
+}
+
+// due reports whether the open file should be rotated before the next write,
+// turning a panic in Rotation or NextRotation into an error
+func (ws *WriteSplitter) due() (due bool, e error) {
+	hook := "Rotation"
+	if ws.Rotation == nil {
+		hook = "NextRotation" // the only user code a policy calls
+	}
+	e = guard(hook, func() error {
+		due = ws.rotation().ShouldRotate(ws.state)
+		return nil
+	})
+	return due, e
+}
+
+// rotation returns what decides when to rotate, Rotation if set
+func (ws *WriteSplitter) rotation() splitcore.RotationPolicy {
+	if ws.Rotation != nil {
+		return ws.Rotation
+	}
+	return ws.policy()
+}
+
+// discardNext closes and removes a file opened ahead of time that was never used
+func (ws *WriteSplitter) discardNext() {
+	if ws.next != nil {
+		ws.next.Close()
+		if ws.Factory == nil {
+			os.Remove(ws.nextName)
+		}
+		ws.release(ws.nextName)
+		ws.next, ws.nextName = nil, ""
+	}
+}
+
+// SetDir changes the dir new files are created in. The change takes effect at
+// the next rotation, or immediately if now is true, in which case the current
+// file is closed and a new one is created in dir.
+func (ws *WriteSplitter) SetDir(dir string, now bool) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	// a dir with tokens may not exist until a file is created in it
+	if e := CheckDir(dir); e != nil && !strings.Contains(dir, "%") {
+		return e
+	}
+
+	ws.nextDir = filepath.Clean(dir)
+	for _, s := range ws.streams {
+		s.mu.Lock()
+		s.nextDir = ws.nextDir
+		s.mu.Unlock()
+	}
+	if now && ws.handle != nil {
+		return ws.rotate()
+	}
+	return nil
+}
+
+// Rotate closes the current file, regardless of the limit, and creates the
+// next one. It returns ErrClosed once ws is closed.
+func (ws *WriteSplitter) Rotate() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.rotate()
+}
+
+// rotate closes the current file and creates the next one
+func (ws *WriteSplitter) rotate() error {
+	_, e := ws.rotateFrom()
+	return e
+}
+
+// rotateFrom is rotate, also returning the final name of the closed file,
+// which may still be finalizing if Async or Compress is set, or "" if there
+// was none or it was removed as empty. OnRotate is given the same name. Once
+// ws is closed there is nothing to rotate and it returns ErrClosed.
+func (ws *WriteSplitter) rotateFrom() (string, error) {
+	if ws.closed {
+		return "", ErrClosed
+	}
+	ws.rotateStreams()
+	had, n := ws.current != "", len(ws.files)
+	if e := ws.closeFile(true); e != ErrNotAFile {
+		ws.report(e) // the next file is created regardless
+	}
+	var prev string
+	if had && len(ws.files) == n { // not removed as empty
+		prev = ws.files[n-1].name
+	}
+	e := ws.create()
+	if e == nil {
+		ws.rotated(prev, ws.current)
+	}
+	ws.prune()
+	return prev, e
+}
+
+// rotated calls OnRotateContext and OnRotate, if set, for a rotation from
+// prev to next. Both are called, as EventsTo is built on OnRotate.
+func (ws *WriteSplitter) rotated(prev, next string) {
+	if ws.OnRotateContext != nil {
+		ws.report(guard("OnRotateContext", func() error {
+			ws.OnRotateContext(ws.context(), prev, next)
+			return nil
+		}))
+	}
+	if ws.OnRotate != nil {
+		ws.report(guard("OnRotate", func() error {
+			ws.OnRotate(prev, next)
+			return nil
+		}))
+	}
+}
+
+// prune applies WarmDir, Retention and RawRetention after a rotation
+func (ws *WriteSplitter) prune() {
+	ws.report(ws.tier()) // a file that can't be moved stays put and is retried next time
+	ws.report(ws.retain())
+	ws.report(ws.retainRaw())
+}
+
 // CheckDir ensure that the given dir exists and is a dir
 func CheckDir(dir string) error {
 	dir = filepath.Clean(dir)
 	stat, e := os.Stat(dir)
-	if os.IsNotExist(e) || !stat.IsDir() || os.IsPermission(e) {
+	if e != nil || !stat.IsDir() { // e.g. not there, not allowed, or under a file
 		return ErrNotADir
 	}
 	return nil
@@ -107,21 +935,180 @@ func CheckDir(dir string) error {
 // createFile is the file creating function that wraps os.Create
 func (ws *WriteSplitter) create() error {
 
-	if ws.Dir == "." { // avoid prefixing files with "."
-		ws.Dir = ""
+	if ws.nextDir != "" { // a pending SetDir
+		ws.Dir, ws.nextDir = ws.nextDir, ""
+		ws.discardNext() // it was opened in the old dir
 	}
 
-	if ws.Prefix == "." { // avoid prefixing files with "."
-		ws.Prefix = ""
-	}
+	var f io.WriteCloser
+	var filename string
+	var resumed splitcore.State
+	var e error
 
-	filename := filepath.Join(ws.Dir, ws.Prefix+time.Now().Format(time.RFC3339Nano))
+	switch {
+	case ws.next != nil:
+		f, filename, e = ws.next, ws.nextName, nil
+		ws.next, ws.nextName = nil, ""
+	case ws.Resume && len(ws.files) == 0 && !ws.encrypted() && ws.Footer == nil && !ws.Sequence: // appending would leave plaintext after the final frame, data after the Footer or numbers out of order
+		if f, filename, resumed = ws.resume(); f == nil {
+			f, filename, e = ws.open()
+		}
+	default:
+		f, filename, e = ws.open()
+	}
 
-	f, e := os.Create(filename)
 	if e == nil {
 		ws.handle = f
+		ws.buffer(f)
+		ws.state.Start(time.Now())
+		ws.state.Add(resumed.Bytes, resumed.Lines)
+		ws.adopt(filename)
+		ws.hold(filename)
+		ws.current = filename
+		ws.totalFiles++
+		ws.files = append(ws.files, fileRecord{
+			name:    filename,
+			created: ws.state.Opened,
+			size:    int64(resumed.Bytes),
+			lines:   int64(resumed.Lines),
+		})
+		if ws.Hash != nil {
+			ws.hasher = ws.Hash()
+			if resumed.Bytes > 0 { // the digest covers what was written before the restart too
+				ws.report(hashFile(filename, ws.hasher))
+			}
+		}
+		ws.resumeIndex(filename, resumed)
+		ws.report(ws.relink(filename)) // a stale link shouldn't stop the writing
+		if resumed.Bytes == 0 {        // a resumed file already has its header
+			e = ws.mark("Header", ws.Header)
+		}
 	} else {
 		ws.handle = nil
 	}
 	return e
 }
+
+// mark writes the boundary record made by fn, Header or Footer, to the open
+// file. Boundary records don't count toward Limit or the FileInfo sizes.
+func (ws *WriteSplitter) mark(hook string, fn func(FileInfo) []byte) error {
+	if fn == nil || ws.current == "" {
+		return nil
+	}
+
+	var b []byte
+	info := ws.files[len(ws.files)-1].info()
+	if e := guard(hook, func() error {
+		b = fn(info)
+		return nil
+	}); e != nil {
+		return e
+	}
+
+	n, e := writeAll(ws.out(), b)
+	ws.spent(n)
+	ws.idxOff += int64(n)
+	if ws.hasher != nil {
+		ws.hasher.Write(b[:n])
+	}
+	return e
+}
+
+// finalTaken returns os.ErrExist if filename is to be renamed, see TempSuffix,
+// to a name that is already taken
+func (ws *WriteSplitter) finalTaken(filename string) error {
+	if ws.TempSuffix == "" {
+		return nil
+	}
+	if _, e := os.Lstat(strings.TrimSuffix(filename, ws.TempSuffix)); e == nil {
+		return os.ErrExist
+	}
+	return nil
+}
+
+// maxCollisions is how many times open tries another name for a file whose
+// name is already taken
+const maxCollisions = 100
+
+// open creates a new, uniquely named file in Dir. Should the name already be
+// taken, "-1", "-2" and so on are appended until it isn't.
+func (ws *WriteSplitter) open() (io.WriteCloser, string, error) {
+
+	if ws.Dir == "." { // avoid prefixing files with "."
+		ws.Dir = ""
+	}
+
+	if ws.Prefix == "." { // avoid prefixing files with "."
+		ws.Prefix = ""
+	}
+
+	now := time.Now()
+	dir := expandDir(ws.Dir, now)
+	if dir != ws.Dir && ws.Factory == nil { // tokens may name a dir that doesn't exist yet
+		if e := os.MkdirAll(dir, ws.dirMode()); e != nil {
+			return nil, "", e
+		}
+	}
+
+	ws.seq++
+	name, e := ws.name(now)
+	if e != nil {
+		return nil, "", e
+	}
+	name += ws.TempSuffix // dropped once the file is complete, see closeFile
+	filename := name
+	if dir != "" {
+		filename = filepath.Join(dir, name)
+	}
+
+	if ws.Factory != nil {
+		var w io.WriteCloser
+		e := guard("Factory", func() (e error) {
+			if cf, ok := ws.Factory.(ContextFileFactory); ok {
+				w, e = cf.CreateContext(ws.context(), filename)
+				return e
+			}
+			w, e = ws.Factory.Create(filename)
+			return e
+		})
+		if e != nil {
+			return nil, filename, e
+		}
+		return ws.encrypt(w, filename)
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL // never readable through the handle, never an existing file
+	if ws.Flags != 0 {
+		flag = os.O_WRONLY | ws.Flags
+	}
+	if ws.AppendOnly {
+		flag |= os.O_APPEND
+	}
+
+	var f *os.File
+	if e = ws.finalTaken(filename); e == nil {
+		f, e = os.OpenFile(filename, flag, ws.fileMode())
+		if os.IsNotExist(e) {
+			f, filename, e = ws.reopen(filename, name, flag)
+		}
+	}
+	base := strings.TrimSuffix(filename, ws.TempSuffix)
+	for i := 1; os.IsExist(e) && i <= maxCollisions; i++ { // e.g. a clock too coarse to tell files apart
+		filename = fmt.Sprintf("%s-%d%s", base, i, ws.TempSuffix)
+		if e = ws.finalTaken(filename); e == nil {
+			f, e = os.OpenFile(filename, flag, ws.fileMode())
+		}
+	}
+	if e != nil {
+		return nil, filename, e
+	}
+
+	if ws.OnCreate != nil {
+		if e = guard("OnCreate", func() error { return ws.OnCreate(f) }); e != nil {
+			f.Close()
+			os.Remove(filename)
+			return nil, filename, e
+		}
+	}
+	return ws.encrypt(f, filename)
+}
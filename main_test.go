@@ -0,0 +1,228 @@
+package writesplitter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+func TestCloseFinalizesInOrder(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(1, dir, "x")
+	ws.Async = true
+
+	var mu sync.Mutex
+	var got []string
+	ws.OnClosed = func(name string, r io.Reader) error {
+		time.Sleep(10 * time.Millisecond) // let Close catch up with the background
+		b, _ := io.ReadAll(r)
+		mu.Lock()
+		got = append(got, string(b))
+		mu.Unlock()
+		return nil
+	}
+	for _, s := range []string{"1", "2", "3"} {
+		ws.Write([]byte(s))
+	}
+	if e := ws.Close(); e != nil {
+		t.Fatal(e)
+	}
+	if len(got) != 3 || got[0] != "1" || got[1] != "2" || got[2] != "3" {
+		t.Errorf("OnClosed saw %v, want [1 2 3]", got)
+	}
+}
+
+func TestPerWriteRetention(t *testing.T) {
+	dir := t.TempDir()
+	ws := SpoolSplitter(dir, "x")
+	ws.Retention = MaxFiles(2)
+	var rotations int
+	ws.OnRotate = func(prev, next string) { rotations++ }
+	for i := 0; i < 5; i++ {
+		if _, e := ws.Write([]byte("a")); e != nil {
+			t.Fatal(e)
+		}
+	}
+	ws.Close()
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("got %d files, want 2", len(entries))
+	}
+	if rotations != 5 {
+		t.Errorf("OnRotate called %d times, want 5", rotations)
+	}
+}
+
+// TestConcurrentMixedCalls is meant for the race detector: Writes racing
+// rotations by Limit, Rotate, Flush and Close must neither race nor lose a
+// line whose Write succeeded, and nothing creates a file once closed.
+func TestConcurrentMixedCalls(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(7, dir, "x")
+	ws.Buffer = 64
+
+	var wrote int64
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, e := ws.Write([]byte("line\n")); e == nil {
+					atomic.AddInt64(&wrote, 1)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			switch {
+			case i == 25:
+				ws.Close() // every Write from here on fails with ErrClosed
+			case i%3 == 0:
+				ws.Rotate()
+			case i%3 == 1:
+				ws.Flush()
+			default:
+				ws.Files()
+			}
+		}
+	}()
+	wg.Wait()
+	if e := ws.Close(); e != nil && e != ErrNotAFile {
+		t.Fatal(e)
+	}
+
+	created := ws.FilesCreated()
+	if _, e := ws.Write([]byte("line\n")); e != ErrClosed {
+		t.Errorf("Write: got %v, want ErrClosed", e)
+	}
+	if e := ws.Rotate(); e != ErrClosed {
+		t.Errorf("Rotate: got %v, want ErrClosed", e)
+	}
+	if e := ws.Barrier(context.Background()); e != ErrClosed {
+		t.Errorf("Barrier: got %v, want ErrClosed", e)
+	}
+	if e := NewGroup(ws).Rotate(); e != ErrClosed {
+		t.Errorf("Group.Rotate: got %v, want ErrClosed", e)
+	}
+	if n := ws.FilesCreated(); n != created {
+		t.Errorf("%d files created after Close", n-created)
+	}
+
+	var lines int64
+	for _, name := range ws.Files() {
+		b, e := os.ReadFile(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		lines += int64(bytes.Count(b, []byte("\n")))
+	}
+	if lines != wrote {
+		t.Errorf("found %d lines, wrote %d", lines, wrote)
+	}
+}
+
+func TestTimeSplitterAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	ws := TimeSplitter(10*time.Millisecond, dir, "x")
+	ws.Write([]byte("a\n"))
+	ws.Close()
+
+	time.Sleep(20 * time.Millisecond) // the Interval is up, but there is nothing to rotate
+	if _, e := ws.Write([]byte("b\n")); e != ErrClosed {
+		t.Errorf("got %v, want ErrClosed", e)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got %d files, want 1", len(entries))
+	}
+}
+
+func TestCheckDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0666)
+
+	tests := []struct {
+		dir  string
+		want error
+	}{
+		{dir, nil},
+		{file, ErrNotADir},
+		{filepath.Join(file, "sub"), ErrNotADir},
+		{filepath.Join(dir, "missing"), ErrNotADir},
+	}
+	for _, tt := range tests {
+		if got := CheckDir(tt.dir); got != tt.want {
+			t.Errorf("CheckDir(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestStreamForksRotation(t *testing.T) {
+	ws := &WriteSplitter{Dir: t.TempDir(), Prefix: "x"}
+	ws.Rotation = splitcore.Adaptive(time.Second, 10, 1000)
+	s := ws.Stream("err").(*WriteSplitter)
+	if s.Rotation == ws.Rotation {
+		t.Error("the stream shares the Rotation of ws")
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	long := strings.Repeat("x", 40*1024) + "\n"
+	down := errors.New("down")
+	tests := []struct {
+		name   string
+		bytes  bool
+		limit  int
+		closed bool
+		in     io.Reader
+		total  int64
+		err    error
+		want   []string // contents of the files, in order
+	}{
+		{"lines", false, 2, false, strings.NewReader("a\nb\nc\n"), 6, nil, []string{"a\nb\n", "c\n"}},
+		{"no final newline", false, 2, false, strings.NewReader("a\nb\nc"), 5, nil, []string{"a\nb\n", "c"}},
+		{"long line in pieces", false, 1, false, strings.NewReader(long), int64(len(long)), nil, []string{long[:32*1024], long[32*1024:]}},
+		{"bytes", true, 4, false, strings.NewReader("abcdefgh"), 8, nil, []string{"abcdefgh"}},
+		{"read error", false, 2, false, io.MultiReader(strings.NewReader("a\n"), iotest.ErrReader(down)), 2, down, []string{"a\n"}},
+		{"closed", false, 2, true, strings.NewReader("a\n"), 0, ErrClosed, nil},
+	}
+	for _, tt := range tests {
+		ws := LineSplitter(tt.limit, t.TempDir(), "x")
+		if tt.bytes {
+			ws = ByteSplitter(tt.limit, t.TempDir(), "x")
+		}
+		if tt.closed {
+			ws.Close()
+		}
+		total, e := ws.ReadFrom(tt.in)
+		if total != tt.total || e != tt.err {
+			t.Errorf("%s: got %d, %v, want %d, %v", tt.name, total, e, tt.total, tt.err)
+		}
+		ws.Close()
+
+		var got []string
+		for _, name := range ws.Files() {
+			b, _ := os.ReadFile(name)
+			got = append(got, string(b))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %d files %.20q, want %d", tt.name, len(got), got, len(tt.want))
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+// NameData is what a WriteSplitter's Template is executed with, e.g.
+//
+//	{{.Prefix}}{{.Time.Format "2006-01-02"}}-{{.Seq}}.log
+type NameData struct {
+	Prefix string
+	Time   time.Time // when the file was created
+	Seq    int       // 1 for the first file created, 2 for the second, and so on
+	Host   string
+}
+
+// SortedFiles returns the files in dir whose names start with prefix, sorted
+// by name, sidecars and all. For a series named with Sortable that is the
+// order they were created in, oldest first.
+func SortedFiles(dir, prefix string) ([]string, error) {
+	entries, e := os.ReadDir(dir) // sorted by name already
+	if e != nil {
+		return nil, e
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), prefix) {
+			names = append(names, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return names, nil
+}
+
+// name returns the filename, without dir, for a file created at now
+func (ws *WriteSplitter) name(now time.Time) (string, error) {
+	if ws.Template == "" && ws.Sortable {
+		ws.nameBuf = splitcore.AppendSortableName(ws.nameBuf[:0], ws.Prefix, now, ws.seq)
+		return string(ws.nameBuf), nil
+	}
+
+	if ws.Template == "" {
+		// build the name in a reused buffer, at short rotation intervals the
+		// allocations of Format and concatenation add up
+		ws.nameBuf = splitcore.AppendName(ws.nameBuf[:0], ws.Prefix, now)
+		return string(ws.nameBuf), nil
+	}
+
+	if ws.tmpl == nil {
+		t, e := template.New("filename").Parse(ws.Template)
+		if e != nil {
+			return "", e
+		}
+		ws.tmpl = t
+	}
+
+	host, _ := os.Hostname()
+	var b strings.Builder
+	e := ws.tmpl.Execute(&b, NameData{
+		Prefix: ws.Prefix,
+		Time:   now,
+		Seq:    ws.seq,
+		Host:   host,
+	})
+	return b.String(), e
+}
+
+// ours reports whether name, a filename without dir, is one a WriteSplitter
+// configured as ws could have created: Prefix and a timestamp, per Sortable,
+// then any of the collision "-N", the SizeInName counts, ".gz", TempSuffix
+// and a sidecar suffix. Files named by a Template can't be told apart from
+// anyone else's, so none are ours.
+func (ws *WriteSplitter) ours(name string) bool {
+	if ws.Template != "" {
+		return false
+	}
+	prefix := ws.Prefix
+	if prefix != "" && prefix != "." {
+		prefix = filepath.Base(prefix)
+	} else {
+		prefix = ""
+	}
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	name = name[len(prefix):]
+
+	for _, suffix := range []string{IndexSuffix, ws.SumSuffix, ws.TempSuffix, ".gz"} {
+		if suffix != "" {
+			name = strings.TrimSuffix(name, suffix)
+		}
+	}
+	name = trimCounts(name)
+
+	if ws.Sortable {
+		if len(name) < len(splitcore.SortableLayout) {
+			return false
+		}
+		if _, e := time.Parse(splitcore.SortableLayout, name[:len(splitcore.SortableLayout)]); e != nil {
+			return false
+		}
+		seq, n, ok := cutNumber(name[len(splitcore.SortableLayout):])
+		if !ok || len(seq) < 10 {
+			return false
+		}
+		_, rest, ok := cutNumber(n)
+		return n == "" || (ok && rest == "")
+	}
+
+	if _, e := time.Parse(time.RFC3339Nano, name); e == nil {
+		return true
+	}
+	i := strings.LastIndexByte(name, '-') // a collision, see maxCollisions
+	if i < 0 || !digits(name[i+1:]) {
+		return false
+	}
+	_, e := time.Parse(time.RFC3339Nano, name[:i])
+	return e == nil
+}
+
+// trimCounts returns name without the "_%dL_%dB" added by SizeInName, if it
+// ends in it
+func trimCounts(name string) string {
+	if !strings.HasSuffix(name, "B") {
+		return name
+	}
+	rest := name[:len(name)-1]
+	i := strings.LastIndexByte(rest, '_')
+	if i < 0 || !digits(rest[i+1:]) {
+		return name
+	}
+	if !strings.HasSuffix(rest[:i], "L") {
+		return name
+	}
+	rest = rest[:i-1]
+	j := strings.LastIndexByte(rest, '_')
+	if j < 0 || !digits(rest[j+1:]) {
+		return name
+	}
+	return rest[:j]
+}
+
+// cutNumber splits s, which must start with "-" and some digits, into the
+// digits and what follows them
+func cutNumber(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, "-") {
+		return "", s, false
+	}
+	i := 1
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[1:i], s[i:], i > 1
+}
+
+// digits reports whether s is a non-empty run of decimal digits
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
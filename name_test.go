@@ -0,0 +1,51 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTemplate(t *testing.T) {
+	host, _ := os.Hostname()
+	today := time.Now().Format("2006-01-02")
+	tests := []struct {
+		tmpl string
+		want []string // names of the first two files
+	}{
+		{"{{.Prefix}}{{.Seq}}.log", []string{"app1.log", "app2.log"}},
+		{`{{.Prefix}}-{{printf "%03d" .Seq}}`, []string{"app-001", "app-002"}},
+		{`{{.Time.Format "2006-01-02"}}-{{.Seq}}.txt`, []string{today + "-1.txt", today + "-2.txt"}},
+		{"{{.Host}}.{{.Seq}}", []string{host + ".1", host + ".2"}},
+		{"fixed.log", []string{"fixed.log", "fixed.log-1"}}, // collisions still get a number
+	}
+	for _, tt := range tests {
+		ws := LineSplitter(1, t.TempDir(), "app")
+		ws.Template = tt.tmpl
+		ws.Write([]byte("a\n"))
+		ws.Write([]byte("b\n"))
+		ws.Close()
+
+		files := ws.Files()
+		if len(files) != len(tt.want) {
+			t.Errorf("%s: got %d files, want %d", tt.tmpl, len(files), len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			if got := filepath.Base(files[i]); got != want {
+				t.Errorf("%s: file %d is %s, want %s", tt.tmpl, i, got, want)
+			}
+		}
+	}
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	for _, tmpl := range []string{"{{.Prefix", "{{.Missing}}"} {
+		ws := LineSplitter(1, t.TempDir(), "app")
+		ws.Template = tmpl
+		if _, e := ws.Write([]byte("a\n")); e == nil {
+			t.Errorf("%s: Write succeeded", tmpl)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package writesplitter
+
+import (
+	"hash"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+// Option configures a WriteSplitter made by New
+type Option func(*WriteSplitter) error
+
+// New returns a WriteSplitter configured by opts, in order, and validated,
+// e.g.
+//
+//	ws, err := New(WithDir("/var/log/app"), WithLimit("100MB"), WithCompression())
+//
+// The fields remain exported, so this is a convenience rather than a guard
+// against the configuration changing later. Making the configuration
+// immutable is deferred to the next major version: unexporting the fields
+// would break every program that sets them, as LineSplitter and the other
+// constructors have always invited. Until then, as with any WriteSplitter,
+// the fields must not be changed once writing has begun.
+func New(opts ...Option) (*WriteSplitter, error) {
+	ws := &WriteSplitter{}
+	if e := ws.apply(opts); e != nil {
+		return nil, e
+	}
+	return ws, nil
+}
+
+// apply applies opts to ws, in order, and validates the result
+func (ws *WriteSplitter) apply(opts []Option) error {
+	for _, opt := range opts {
+		if e := opt(ws); e != nil {
+			return e
+		}
+	}
+	return ws.Validate()
+}
+
+// WithLineLimit splits files every n lines
+func WithLineLimit(n int) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Limit, ws.Bytes = n, false
+		return nil
+	}
+}
+
+// WithByteLimit splits files every n bytes
+func WithByteLimit(n int) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Limit, ws.Bytes = n, true
+		return nil
+	}
+}
+
+// WithLimit splits files per a limit as understood by ParseLimit
+func WithLimit(limit string) Option {
+	return func(ws *WriteSplitter) error {
+		n, bytes, e := ParseLimit(limit)
+		ws.Limit, ws.Bytes = n, bytes
+		return e
+	}
+}
+
+// WithInterval splits files once they've been written to for d
+func WithInterval(d time.Duration) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Interval = d
+		return nil
+	}
+}
+
+// WithRotation rotates files as p decides, see WriteSplitter.Rotation
+func WithRotation(p splitcore.RotationPolicy) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Rotation = p
+		return nil
+	}
+}
+
+// WithDir sets the dir files are created in
+func WithDir(dir string) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Dir = filepath.Clean(dir)
+		return nil
+	}
+}
+
+// WithPrefix sets the prefix of every filename
+func WithPrefix(prefix string) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Prefix = filepath.Clean(prefix)
+		return nil
+	}
+}
+
+// WithCompression gzips rotated files in the background
+func WithCompression() Option {
+	return func(ws *WriteSplitter) error {
+		ws.Compress = true
+		return nil
+	}
+}
+
+// WithAsync finalizes rotated files off the Write path
+func WithAsync() Option {
+	return func(ws *WriteSplitter) error {
+		ws.Async = true
+		return nil
+	}
+}
+
+// WithHash computes a digest of every file with fn, see WriteSplitter.Hash
+func WithHash(fn func() hash.Hash) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Hash = fn
+		return nil
+	}
+}
+
+// WithShipping hands every completed file to fn, see WriteSplitter.OnClosed
+func WithShipping(fn func(name string, r io.Reader) error) Option {
+	return func(ws *WriteSplitter) error {
+		ws.OnClosed = fn
+		return nil
+	}
+}
+
+// WithRetention deletes completed files chosen by p
+func WithRetention(p RetentionPolicy) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Retention = p
+		return nil
+	}
+}
+
+// WithKey encrypts every file with key, see WriteSplitter.Key
+func WithKey(key []byte) Option {
+	return func(ws *WriteSplitter) error {
+		ws.Key = key
+		return nil
+	}
+}
+
+// WithConfig applies fn to the WriteSplitter, for any setting that has no
+// Option of its own
+func WithConfig(fn func(*WriteSplitter)) Option {
+	return func(ws *WriteSplitter) error {
+		fn(ws)
+		return nil
+	}
+}
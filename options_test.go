@@ -0,0 +1,40 @@
+package writesplitter
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewTempOptions(t *testing.T) {
+	ws, dir, e := NewTemp("ws-", WithLineLimit(1), WithKey(testKey))
+	if e != nil {
+		t.Fatal(e)
+	}
+	ws.Write([]byte("a\n"))
+	ws.Write([]byte("b\n"))
+	if n := len(ws.Files()); n != 2 {
+		t.Errorf("got %d files, want 2", n)
+	}
+	if e := ws.Close(); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := os.Stat(dir); !os.IsNotExist(e) {
+		t.Error("temp dir left behind")
+	}
+
+	if _, dir, e = NewTemp("ws-", WithKey([]byte("short"))); e == nil {
+		t.Error("bad Key validated")
+	} else if dir != "" {
+		t.Errorf("got dir %q on failure", dir)
+	}
+}
+
+func TestBuilderWith(t *testing.T) {
+	ws, e := Build().InDir(t.TempDir()).EncryptedWith(testKey).With(WithLimit("10 lines")).New()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if ws.Limit != 10 || ws.Key == nil {
+		t.Errorf("got Limit %d and Key %v", ws.Limit, ws.Key)
+	}
+}
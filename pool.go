@@ -0,0 +1,32 @@
+package writesplitter
+
+import "sync"
+
+// Pool hands out configured WriteSplitters and recycles them once they're
+// returned. It is meant for batch systems that create and discard many
+// short-lived writers.
+type Pool struct {
+	pool sync.Pool
+}
+
+// NewPool returns a Pool that uses fn to build new WriteSplitters
+func NewPool(fn func() *WriteSplitter) *Pool {
+	return &Pool{
+		pool: sync.Pool{New: func() interface{} { return fn() }},
+	}
+}
+
+// Get returns a WriteSplitter from the pool, building one if none is available
+func (p *Pool) Get() *WriteSplitter {
+	return p.pool.Get().(*WriteSplitter)
+}
+
+// Put resets ws and returns it to the pool. ws must not be used afterwards.
+// If Reset fails ws is dropped rather than pooled and the error returned.
+func (p *Pool) Put(ws *WriteSplitter) error {
+	if e := ws.Reset(); e != nil {
+		return e
+	}
+	p.pool.Put(ws)
+	return nil
+}
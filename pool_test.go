@@ -0,0 +1,40 @@
+package writesplitter
+
+import "testing"
+
+func TestResetClearsState(t *testing.T) {
+	ws := LineSplitter(1, t.TempDir(), "x")
+	ws.Sequence = true
+	ws.Write([]byte("a\n"))
+	ws.Write([]byte("b\n"))
+	ws.Stream("err").Write([]byte("c\n"))
+	if e := ws.Reset(); e != nil {
+		t.Fatal(e)
+	}
+
+	if n := ws.FilesCreated(); n != 0 {
+		t.Errorf("FilesCreated is %d after Reset", n)
+	}
+	if n := ws.TotalBytes(); n != 0 {
+		t.Errorf("TotalBytes is %d after Reset", n)
+	}
+	if len(ws.streams) != 0 {
+		t.Error("streams left open after Reset")
+	}
+
+	ws.Write([]byte("d\n"))
+	ws.Close()
+	if st := ws.Export(); st.Records != 1 || st.Seq != 1 {
+		t.Errorf("Sequence and Seq carry on from before Reset: %+v", st)
+	}
+}
+
+func TestPoolRecycles(t *testing.T) {
+	dir := t.TempDir()
+	p := NewPool(func() *WriteSplitter { return LineSplitter(0, dir, "x") })
+	ws := p.Get()
+	ws.Write([]byte("a\n"))
+	if e := p.Put(ws); e != nil {
+		t.Fatal(e)
+	}
+}
@@ -0,0 +1,94 @@
+package writesplitter
+
+import (
+	"crypto/rand"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Preflight exercises the configured pipeline once, end to end, so a bad
+// configuration surfaces at startup rather than with the first real traffic.
+// It validates the configuration, then creates, writes, rotates and closes
+// files in a temporary dir inside Dir (and WarmDir), compressing, tiering and
+// applying retention as configured. Dir itself is created only if writing
+// would create it, see MkdirAll. Nothing outside those dirs is touched:
+// OnClosed and OnClosedContext are replaced with a dry run that only reads
+// each file, a Factory with the temporary dir, KeyFunc with a throwaway key
+// and Rotation, which may hold state, with the explicit rotation; OnCreate,
+// OnRotate, OnRotateContext, OnFinalized and OnSoftLimit aren't called.
+// Everything is removed afterwards and ws itself is left untouched.
+func (ws *WriteSplitter) Preflight() error {
+	if e := ws.Validate(); e != nil {
+		return e
+	}
+
+	ws.mu.Lock()
+	c := ws.clone()
+	ws.mu.Unlock()
+
+	base := orDot(expandDir(c.Dir, time.Now()))
+	if base != orDot(c.Dir) || c.MkdirAll || c.MissingDir == RecreateMissingDir { // as open would
+		if e := os.MkdirAll(base, c.dirMode()); e != nil {
+			return e
+		}
+	}
+	dir, e := os.MkdirTemp(base, ".preflight-")
+	if e != nil {
+		return e
+	}
+	defer os.RemoveAll(dir)
+	c.Dir = dir
+	c.Link = "" // leave the real link alone
+
+	if c.WarmDir != "" {
+		warm, e := os.MkdirTemp(c.WarmDir, ".preflight-")
+		if e != nil {
+			return e
+		}
+		defer os.RemoveAll(warm)
+		c.WarmDir = warm
+	}
+
+	var mu sync.Mutex
+	var reported error
+	c.OnError = func(e error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if reported == nil {
+			reported = e
+		}
+	}
+	c.OnClosed = func(name string, r io.Reader) error {
+		_, e := io.Copy(io.Discard, r)
+		return e
+	}
+	c.OnClosedContext = nil
+	c.OnCreate, c.OnRotate, c.OnRotateContext, c.OnFinalized = nil, nil, nil, nil
+	c.OnSoftLimit, c.SoftLimit = nil, 0
+	c.Factory, c.Rotation = nil, nil
+	if c.KeyFunc != nil {
+		c.Key, c.KeyFunc = make([]byte, 32), nil
+		if _, e = rand.Read(c.Key); e != nil {
+			return e
+		}
+	}
+
+	if _, e = c.Write([]byte("preflight\n")); e != nil {
+		return e
+	}
+	if e = c.Rotate(); e != nil {
+		return e
+	}
+	if _, e = c.Write([]byte("preflight\n")); e != nil {
+		return e
+	}
+	if e = c.Close(); e != nil {
+		return e
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return reported
+}
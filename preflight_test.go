@@ -0,0 +1,74 @@
+package writesplitter
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+func TestPreflightCallsNoHooks(t *testing.T) {
+	dir := t.TempDir()
+	var called []string
+	hook := func(name string) { called = append(called, name) }
+
+	ws := LineSplitter(0, dir, "x")
+	ws.OnRotate = func(string, string) { hook("OnRotate") }
+	ws.OnFinalized = func(string) error { hook("OnFinalized"); return nil }
+	ws.OnCreate = func(*os.File) error { hook("OnCreate"); return nil }
+	ws.KeyFunc = func(string) ([]byte, error) { hook("KeyFunc"); return testKey, nil }
+	ws.Rotation = splitcore.RotationFunc(func(splitcore.State) bool { hook("Rotation"); return false })
+	ws.OnClosed = func(string, io.Reader) error { hook("OnClosed"); return nil }
+
+	if e := ws.Preflight(); e != nil {
+		t.Fatal(e)
+	}
+	if len(called) > 0 {
+		t.Errorf("Preflight called %v", called)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Preflight left %d files", len(entries))
+	}
+}
+
+func TestPreflightFactory(t *testing.T) {
+	var created int
+	ws := LineSplitter(0, t.TempDir(), "x")
+	ws.Factory = FileFactoryFunc(func(string) (io.WriteCloser, error) {
+		created++
+		return nil, nil
+	})
+	if e := ws.Preflight(); e != nil {
+		t.Fatal(e)
+	}
+	if created > 0 {
+		t.Error("Preflight created files through the Factory")
+	}
+}
+
+func TestPreflightDir(t *testing.T) {
+	wd, _ := os.Getwd()
+	dir := t.TempDir()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	ws := LineSplitter(0, "", "x")
+	if e := ws.Preflight(); e != nil {
+		t.Fatal(e)
+	}
+
+	ws = LineSplitter(0, "logs/%Y", "x") // tokens, created as need be
+	if e := ws.Preflight(); e != nil {
+		t.Fatal(e)
+	}
+
+	ws = LineSplitter(0, "missing", "x")
+	if e := ws.Preflight(); e == nil {
+		t.Error("Preflight passed for a Dir that writing would fail in")
+	}
+	ws.MkdirAll = true
+	if e := ws.Preflight(); e != nil {
+		t.Error(e)
+	}
+}
@@ -0,0 +1,45 @@
+package writesplitter
+
+import (
+	"io"
+	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+// DailyCompressedKeep30 returns a Builder for files that are split at
+// midnight UTC, gzipped once complete and deleted once last written to more
+// than 30 days ago, e.g.
+//
+//	ws, err := DailyCompressedKeep30("/var/log/app", "app-").New()
+func DailyCompressedKeep30(dir, prefix string) *Builder {
+	return Build().
+		InDir(dir).
+		WithPrefix(prefix).
+		SplitAt(splitcore.Aligned(24 * time.Hour)).
+		Compressed().
+		WithRetention(MaxAge(30 * 24 * time.Hour))
+}
+
+// HundredMBKeep10GB returns a Builder for files of 100MB, of which the most
+// recent 10GB are kept
+func HundredMBKeep10GB(dir, prefix string) *Builder {
+	return Build().
+		InDir(dir).
+		WithPrefix(prefix).
+		SplitByBytes(100e6).
+		WithRetention(MaxBytes(10e9))
+}
+
+// PerHourShipToS3 returns a Builder for files that are split hourly and, once
+// complete, handed to upload, e.g. an S3 PutObject keyed on the file's base
+// name. Files are finalized in the background so an upload doesn't hold up
+// writing. They are left on disk, add WithRetention to remove them.
+func PerHourShipToS3(dir, prefix string, upload func(name string, r io.Reader) error) *Builder {
+	return Build().
+		InDir(dir).
+		WithPrefix(prefix).
+		SplitEvery(time.Hour).
+		Background().
+		ShipWith(upload)
+}
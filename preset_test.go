@@ -0,0 +1,22 @@
+package writesplitter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyCompressedKeep30(t *testing.T) {
+	ws, e := DailyCompressedKeep30(t.TempDir(), "app-").New()
+	if e != nil {
+		t.Fatal(e)
+	}
+	opened := time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)
+	if got := ws.NextRotation(opened); !got.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("a file opened at %v rotates at %v, not midnight", opened, got)
+	}
+	old := FileInfo{Name: "old", Last: time.Now().Add(-31 * 24 * time.Hour)}
+	recent := FileInfo{Name: "recent", Last: time.Now().Add(-29 * 24 * time.Hour)}
+	if drop := ws.Retention.Evaluate([]FileInfo{old, recent}); len(drop) != 1 || drop[0].Name != "old" {
+		t.Errorf("Retention chose %v, want only the file older than 30 days", drop)
+	}
+}
@@ -0,0 +1,208 @@
+package writesplitter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by Write when writing would take the files of
+// a WriteSplitter past MaxTotalBytes
+var ErrQuotaExceeded = errors.New("WriteSplitter: disk quota exceeded")
+
+// diskQuota is the MaxTotalBytes budget a WriteSplitter shares with its
+// streams. It counts everything on disk in Dir and WarmDir named as one of
+// them names its files, see ours, so raw copies, sidecars, boundary records
+// and the files of earlier runs and of streams all count, while files put
+// there by anything else are neither counted nor pruned. Rather than stat every file on every Write,
+// the bytes written since the dirs were last measured are added to what was
+// measured, and the dirs are measured again after every rotation, as
+// finalizing changes sizes, or once the estimate runs over the budget.
+type diskQuota struct {
+	mu     sync.Mutex
+	used   int64           // bytes on disk, as of the last measure plus writes since
+	stale  bool            // used needs measuring again
+	busy   map[string]bool // files open or being finalized, never pruned
+	dirs   []string
+	owners []*WriteSplitter // ws and its streams
+}
+
+// diskQuota returns the budget of ws, creating it on first use. Streams are
+// given the budget of the WriteSplitter they belong to.
+func (ws *WriteSplitter) diskQuota() *diskQuota {
+	if ws.quotaOf == nil {
+		ws.quotaOf = &diskQuota{stale: true, busy: make(map[string]bool), owners: []*WriteSplitter{ws}}
+	}
+	return ws.quotaOf
+}
+
+// share gives s, a stream of ws, the budget of ws
+func (ws *WriteSplitter) share(s *WriteSplitter) {
+	q := ws.diskQuota()
+	q.mu.Lock()
+	q.owners = append(q.owners, s)
+	q.mu.Unlock()
+	s.quotaOf = q
+}
+
+// owns reports whether name, without dir, is a file of one of the owners
+func (q *diskQuota) owns(name string) bool {
+	for _, ws := range q.owners {
+		if ws.ours(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// quota makes room for n more bytes under MaxTotalBytes, deleting the oldest
+// completed files if PruneToQuota is set, and returns ErrQuotaExceeded if it
+// can't
+func (ws *WriteSplitter) quota(n int) error {
+	if ws.MaxTotalBytes <= 0 {
+		return nil
+	}
+
+	q := ws.diskQuota()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.dirs = q.dirs[:0]
+	q.dirs = append(q.dirs, expandDir(ws.Dir, time.Now()))
+	if ws.WarmDir != "" {
+		q.dirs = append(q.dirs, ws.WarmDir)
+	}
+
+	if q.stale || q.used+int64(n) > ws.MaxTotalBytes {
+		files := q.measure(ws)
+		if q.used+int64(n) > ws.MaxTotalBytes && ws.PruneToQuota {
+			ws.report(q.prune(ws, files, ws.MaxTotalBytes-int64(n)))
+		}
+	}
+	if q.used+int64(n) > ws.MaxTotalBytes {
+		return ErrQuotaExceeded
+	}
+	q.used += int64(n)
+	return nil
+}
+
+// spent adds n bytes written outside of quota, e.g. a Header, to the estimate
+func (ws *WriteSplitter) spent(n int) {
+	if ws.MaxTotalBytes <= 0 {
+		return
+	}
+	q := ws.diskQuota()
+	q.mu.Lock()
+	q.used += int64(n)
+	q.mu.Unlock()
+}
+
+// hold keeps the named file from being pruned until it is released, see
+// finalize
+func (ws *WriteSplitter) hold(name string) {
+	if ws.MaxTotalBytes <= 0 || name == "" {
+		return
+	}
+	q := ws.diskQuota()
+	q.mu.Lock()
+	q.busy[name] = true
+	q.stale = true // a new file, and the one before it finalizing
+	q.mu.Unlock()
+}
+
+// release is the counterpart of hold
+func (ws *WriteSplitter) release(name string) {
+	if ws.MaxTotalBytes <= 0 || ws.quotaOf == nil {
+		return
+	}
+	q := ws.quotaOf
+	q.mu.Lock()
+	delete(q.busy, name)
+	q.stale = true
+	q.mu.Unlock()
+}
+
+// quotaFile is a file counted toward the budget
+type quotaFile struct {
+	name string
+	size int64
+	mod  time.Time
+}
+
+// measure sets used to what the files counted toward the budget take up on
+// disk and returns those that could be pruned, oldest first
+func (q *diskQuota) measure(ws *WriteSplitter) []quotaFile {
+	q.used, q.stale = 0, false
+	var prunable []quotaFile
+	for _, dir := range q.dirs {
+		entries, e := os.ReadDir(orDot(dir))
+		if e != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !q.owns(entry.Name()) {
+				continue
+			}
+			info, e := entry.Info()
+			if e != nil {
+				continue
+			}
+			q.used += info.Size()
+
+			name := filepath.Join(dir, entry.Name())
+			if !q.busy[name] && !ws.isSidecar(name) && !(ws.TempSuffix != "" && strings.HasSuffix(name, ws.TempSuffix)) {
+				prunable = append(prunable, quotaFile{name, info.Size(), info.ModTime()})
+			}
+		}
+	}
+	sort.SliceStable(prunable, func(i, j int) bool { return prunable[i].mod.Before(prunable[j].mod) })
+	return prunable
+}
+
+// prune deletes files, oldest first, and their sidecars until used is at most
+// limit, dropping the records ws has of them
+func (q *diskQuota) prune(ws *WriteSplitter, files []quotaFile, limit int64) error {
+	var err error
+	gone := make(map[string]bool)
+	for _, f := range files {
+		if q.used <= limit {
+			break
+		}
+		before := q.used
+		for _, side := range ws.sidecars(f.name) {
+			if info, e := os.Stat(side); e == nil {
+				q.used -= info.Size()
+			}
+		}
+		q.used -= f.size
+		if e := ws.remove(f.name); e != nil && !os.IsNotExist(e) {
+			q.used = before
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		gone[f.name] = true
+	}
+
+	keep := ws.files[:0]
+	for _, rec := range ws.files {
+		if !gone[rec.name] {
+			keep = append(keep, rec)
+		}
+	}
+	ws.files = keep
+	return err
+}
+
+// orDot returns dir, or "." for the working dir
+func orDot(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
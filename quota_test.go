@@ -0,0 +1,69 @@
+package writesplitter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// du returns the bytes taken up by the files in dir
+func du(dir string) (n int64) {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if info, e := entry.Info(); e == nil {
+			n += info.Size()
+		}
+	}
+	return n
+}
+
+func TestQuotaPrunes(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "x2001-02-03T04:05:06Z"), bytes.Repeat([]byte("o"), 500), 0644) // an earlier run
+	os.WriteFile(filepath.Join(dir, "x-earlier-run"), bytes.Repeat([]byte("o"), 500), 0644)         // someone else's
+
+	ws := ByteSplitter(100, dir, "x")
+	ws.MaxTotalBytes, ws.PruneToQuota = 1000, true
+	ws.IndexEvery = 1
+	ws.Header = func(FileInfo) []byte { return []byte("header\n") }
+	line := append(bytes.Repeat([]byte("a"), 49), '\n')
+	for i := 0; i < 60; i++ {
+		if _, e := ws.Write(line); e != nil {
+			t.Fatal(i, e)
+		}
+		if _, e := ws.Stream("err").Write(line); e != nil {
+			t.Fatal(i, e)
+		}
+		// sidecars and headers are only measured after the fact, give or
+		// take a file's worth, and x-earlier-run isn't counted at all
+		if n := du(dir) - 500; n > ws.MaxTotalBytes+200 {
+			t.Fatalf("write %d: %d bytes on disk", i, n)
+		}
+	}
+	ws.Close()
+	if _, e := os.Stat(filepath.Join(dir, "x2001-02-03T04:05:06Z")); !os.IsNotExist(e) {
+		t.Error("the file of an earlier run was not pruned")
+	}
+	if _, e := os.Stat(filepath.Join(dir, "x-earlier-run")); e != nil {
+		t.Error("a file not named by ws was pruned")
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	dir := t.TempDir()
+	ws := ByteSplitter(100, dir, "x")
+	ws.MaxTotalBytes = 300
+	line := append(bytes.Repeat([]byte("a"), 49), '\n')
+	var e error
+	for i := 0; i < 10 && e == nil; i++ {
+		_, e = ws.Write(line)
+	}
+	ws.Close()
+	if e != ErrQuotaExceeded {
+		t.Errorf("got %v, want ErrQuotaExceeded", e)
+	}
+	if n := du(dir); n > 300 {
+		t.Errorf("%d bytes on disk", n)
+	}
+}
@@ -0,0 +1,112 @@
+package writesplitter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// RecordReader reads records back out of a set of files produced by a
+// WriteSplitter. The files are read in the order given as one continuous
+// stream, so a record split across two files is returned whole. Files ending
+// in ".gz" are decompressed as they're read unless Raw is set.
+type RecordReader struct {
+	Delim byte // records are terminated by Delim, '\n' by default
+	Raw   bool // read files as they are on disk, without decompressing
+	sr    *seriesReader
+	r     *bufio.Reader
+}
+
+// NewRecordReader returns a RecordReader over files, see WriteSplitter.Files
+func NewRecordReader(files ...string) *RecordReader {
+	rr := &RecordReader{Delim: '\n'}
+	rr.sr = &seriesReader{files: files, raw: &rr.Raw}
+	rr.r = bufio.NewReader(rr.sr)
+	return rr
+}
+
+// Next returns the next record without its delimiter. A final record that is
+// missing its delimiter is still returned. At the end of the set Next returns
+// io.EOF.
+func (rr *RecordReader) Next() ([]byte, error) {
+	rec, e := rr.r.ReadBytes(rr.Delim)
+	if len(rec) > 0 && rec[len(rec)-1] == rr.Delim {
+		return rec[:len(rec)-1], nil
+	}
+	if e == io.EOF && len(rec) > 0 {
+		return rec, nil
+	}
+	return rec, e
+}
+
+// Close closes the file currently being read
+func (rr *RecordReader) Close() error {
+	return rr.sr.Close()
+}
+
+// seriesReader is an io.Reader over files opened one at a time
+type seriesReader struct {
+	files []string
+	raw   *bool // see RecordReader.Raw
+	cur   *os.File
+	r     io.Reader // reads cur, decompressing if need be
+	skip  int64     // where to start reading the first file, see SeekRecord
+}
+
+func (sr *seriesReader) Read(p []byte) (int, error) {
+	for {
+		if sr.cur == nil {
+			if len(sr.files) == 0 {
+				return 0, io.EOF
+			}
+			if e := sr.open(sr.files[0]); e != nil {
+				return 0, e
+			}
+			sr.files = sr.files[1:]
+		}
+
+		n, e := sr.r.Read(p)
+		if e == io.EOF {
+			sr.Close()
+			if n == 0 {
+				continue
+			}
+			e = nil
+		}
+		return n, e
+	}
+}
+
+// open makes name the file being read
+func (sr *seriesReader) open(name string) error {
+	f, e := os.Open(name)
+	if e != nil {
+		return e
+	}
+
+	sr.cur, sr.r = f, f
+	if !*sr.raw && strings.HasSuffix(name, ".gz") {
+		if sr.r, e = gzip.NewReader(f); e != nil {
+			f.Close()
+			sr.cur, sr.r = nil, nil
+			return e
+		}
+	}
+	if e = skipTo(f, sr.r, sr.skip); e != nil {
+		sr.Close()
+		return e
+	}
+	sr.skip = 0
+	return nil
+}
+
+func (sr *seriesReader) Close() error {
+	if sr.cur == nil {
+		return nil
+	}
+	e := sr.cur.Close()
+	sr.cur, sr.r = nil, nil
+	return e
+}
@@ -0,0 +1,89 @@
+package writesplitter
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+// resume reopens the most recently modified file in Dir named as ws names
+// them, see ours and Resume, and returns it along with what has been written to it. The
+// lines are counted as '\n's, whatever Newlines says, as the writes that made
+// them are long gone. It returns nil if there's no such file or it can't be
+// reopened, in which case a new file is created as usual.
+func (ws *WriteSplitter) resume() (io.WriteCloser, string, splitcore.State) {
+	dir := expandDir(ws.Dir, time.Now())
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, e := os.ReadDir(dir)
+	if e != nil {
+		return nil, "", splitcore.State{}
+	}
+
+	var latest os.FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !ws.ours(name) || strings.HasSuffix(name, ".gz") || ws.isSidecar(name) {
+			continue
+		}
+		info, e := entry.Info()
+		if e == nil && (latest == nil || info.ModTime().After(latest.ModTime())) {
+			latest = info
+		}
+	}
+	if latest == nil {
+		return nil, "", splitcore.State{}
+	}
+
+	filename := filepath.Join(dir, latest.Name())
+	lines, e := countLines(filename)
+	if e != nil {
+		return nil, "", splitcore.State{}
+	}
+	f, e := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, ws.fileMode())
+	if e != nil {
+		return nil, "", splitcore.State{}
+	}
+	return f, filename, splitcore.State{Lines: lines, Bytes: int(latest.Size())}
+}
+
+// hashFile writes the contents of the named file to h
+func hashFile(name string, h hash.Hash) error {
+	f, e := os.Open(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	_, e = io.Copy(h, f)
+	return e
+}
+
+// countLines counts the '\n's in the named file
+func countLines(name string) (int, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return 0, e
+	}
+	defer f.Close()
+
+	var n int
+	buf := make([]byte, 32*1024)
+	for {
+		m, e := f.Read(buf)
+		n += bytes.Count(buf[:m], []byte{'\n'})
+		if e == io.EOF {
+			return n, nil
+		}
+		if e != nil {
+			return n, e
+		}
+	}
+}
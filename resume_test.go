@@ -0,0 +1,75 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResumeOwnFilesOnly(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "")
+	ws.Write([]byte("one\n"))
+	ws.Close()
+
+	time.Sleep(10 * time.Millisecond) // config.yaml is the most recently modified
+	config := filepath.Join(dir, "config.yaml")
+	os.WriteFile(config, []byte("a: b\n"), 0666)
+
+	ws = LineSplitter(0, dir, "")
+	ws.Resume = true
+	ws.Write([]byte("two\n"))
+	ws.Close()
+
+	if b, _ := os.ReadFile(config); string(b) != "a: b\n" {
+		t.Errorf("config.yaml holds %q", b)
+	}
+	if b, _ := os.ReadFile(ws.Files()[0]); string(b) != "one\ntwo\n" {
+		t.Errorf("resumed file holds %q", b)
+	}
+}
+
+func TestResumeFooter(t *testing.T) {
+	dir := t.TempDir()
+	footer := func(FileInfo) []byte { return []byte("end\n") }
+	ws := LineSplitter(0, dir, "x")
+	ws.Footer = footer
+	ws.Write([]byte("one\n"))
+	ws.Close()
+
+	ws = LineSplitter(0, dir, "x")
+	ws.Resume, ws.Footer = true, footer
+	if _, ok := ws.Validate().(*ConfigError); !ok {
+		t.Error("Validate allows Resume with a Footer")
+	}
+	ws.Write([]byte("two\n"))
+	ws.Close()
+
+	if b, _ := os.ReadFile(ws.Files()[0]); string(b) != "two\nend\n" {
+		t.Errorf("got %q, want a new file", b)
+	}
+}
+
+func TestResumeSequence(t *testing.T) {
+	dir := t.TempDir()
+	ws := LineSplitter(0, dir, "x")
+	ws.Sequence = true
+	ws.Write([]byte("one\n"))
+	ws.Close()
+	st := ws.Export()
+
+	ws = LineSplitter(0, dir, "x")
+	ws.Resume, ws.Sequence = true, true
+	if _, ok := ws.Validate().(*ConfigError); !ok {
+		t.Error("Validate allows Resume with a Sequence")
+	}
+	ws.Import(st)
+	ws.Write([]byte("two\n"))
+	ws.Close()
+
+	files := ws.Files()
+	if b, _ := os.ReadFile(files[len(files)-1]); string(b) != "2 two\n" {
+		t.Errorf("got %q, want a new file carrying on from the Import", b)
+	}
+}
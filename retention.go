@@ -0,0 +1,277 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileInfo describes a file created by a WriteSplitter
+type FileInfo struct {
+	Name    string
+	Created time.Time // when the file started being written to
+	Size    int64     // bytes written
+	Lines   int64     // lines written, see WriteSplitter.Newlines
+	First   time.Time // time of the first write, zero if never written to
+	Last    time.Time // time of the last write, zero if never written to
+	Digest  []byte    // see WriteSplitter.Hash
+
+	Meta map[string]string // see WriteSplitter.SetFileMeta
+}
+
+// RetentionPolicy is given the completed files of a WriteSplitter, oldest
+// first, and returns the ones that should be deleted
+type RetentionPolicy interface {
+	Evaluate(files []FileInfo) []FileInfo
+}
+
+// RetentionFunc is an adapter to allow the use of ordinary functions as a
+// RetentionPolicy
+type RetentionFunc func(files []FileInfo) []FileInfo
+
+// Evaluate calls fn(files)
+func (fn RetentionFunc) Evaluate(files []FileInfo) []FileInfo {
+	return fn(files)
+}
+
+// MaxFiles returns a RetentionPolicy that keeps the n most recent completed
+// files. The open file is not counted.
+func MaxFiles(n int) RetentionPolicy {
+	return RetentionFunc(func(files []FileInfo) []FileInfo {
+		if len(files) <= n {
+			return nil
+		}
+		return files[:len(files)-n]
+	})
+}
+
+// MaxAge returns a RetentionPolicy that deletes completed files last written
+// to more than d ago
+func MaxAge(d time.Duration) RetentionPolicy {
+	return RetentionFunc(func(files []FileInfo) []FileInfo {
+		var old []FileInfo
+		for _, fi := range files {
+			last := fi.Last
+			if last.IsZero() {
+				last = fi.Created
+			}
+			if time.Since(last) > d {
+				old = append(old, fi)
+			}
+		}
+		return old
+	})
+}
+
+// MaxBytes returns a RetentionPolicy that keeps the most recent completed
+// files totalling at most n bytes, as written rather than as compressed. The
+// open file is not counted.
+func MaxBytes(n int64) RetentionPolicy {
+	return RetentionFunc(func(files []FileInfo) []FileInfo {
+		var total int64
+		for i := len(files) - 1; i >= 0; i-- {
+			total += files[i].Size
+			if total > n {
+				return files[:i+1]
+			}
+		}
+		return nil
+	})
+}
+
+// AnyOf returns a RetentionPolicy that deletes the files chosen by any of
+// policies, e.g. AnyOf(MaxFiles(10), MaxAge(24*time.Hour))
+func AnyOf(policies ...RetentionPolicy) RetentionPolicy {
+	return RetentionFunc(func(files []FileInfo) []FileInfo {
+		seen := make(map[string]bool)
+		var drop []FileInfo
+		for _, p := range policies {
+			for _, fi := range p.Evaluate(files) {
+				if !seen[fi.Name] {
+					seen[fi.Name] = true
+					drop = append(drop, fi)
+				}
+			}
+		}
+		return drop
+	})
+}
+
+// info returns the exported description of rec
+func (rec fileRecord) info() FileInfo {
+	return FileInfo{
+		Name:    rec.name,
+		Created: rec.created,
+		Size:    rec.size,
+		Lines:   rec.lines,
+		First:   rec.first,
+		Last:    rec.last,
+		Digest:  rec.digest,
+		Meta:    copyMeta(rec.meta),
+	}
+}
+
+// copyMeta returns a copy of m, so a FileInfo can't change the record it came from
+func copyMeta(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// retain deletes the completed files chosen by Retention. Files that fail to
+// delete are kept and offered to Retention again at the next rotation.
+func (ws *WriteSplitter) retain() error {
+	if ws.Retention == nil {
+		return nil
+	}
+
+	var done []FileInfo
+	for _, rec := range ws.files {
+		if ws.finalized(rec.name) {
+			done = append(done, rec.info())
+		}
+	}
+
+	var chosen []FileInfo
+	if e := guard("Retention", func() error {
+		chosen = ws.Retention.Evaluate(done)
+		return nil
+	}); e != nil {
+		return e
+	}
+
+	drop := make(map[string]bool)
+	for _, fi := range chosen {
+		if ws.finalized(fi.Name) {
+			drop[fi.Name] = true
+		}
+	}
+	if len(drop) == 0 {
+		return nil
+	}
+
+	var err error
+	keep := ws.files[:0]
+	for _, rec := range ws.files {
+		if drop[rec.name] {
+			e := ws.remove(rec.name)
+			if e == nil || os.IsNotExist(e) {
+				continue
+			}
+			if err == nil {
+				err = e
+			}
+		}
+		keep = append(keep, rec)
+	}
+	ws.files = keep
+	return err
+}
+
+// adopt starts the list of files with those left in Dir by earlier runs, so
+// Retention also deletes them rather than disk use growing with every restart.
+// It takes only files named as ws names them, see ours, oldest first by
+// modification time, leaving out sidecars, raw copies and files still named
+// with TempSuffix. opened is the file just created, which isn't one of them.
+// Nothing is adopted without a Prefix, as the files of anything else writing
+// timestamped files to Dir would be, or once the list has been started, e.g.
+// by Import, which is how files named any other way are handed over.
+func (ws *WriteSplitter) adopt(opened string) {
+	if ws.Retention == nil || ws.Factory != nil || ws.Ephemeral || len(ws.files) > 0 || ws.Prefix == "" || ws.Prefix == "." {
+		return
+	}
+
+	dir := filepath.Dir(opened)
+	entries, e := os.ReadDir(dir)
+	if e != nil {
+		return
+	}
+	taken := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		taken[entry.Name()] = true
+	}
+	var recs []fileRecord
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case !entry.Type().IsRegular() || !ws.ours(name) || ws.isSidecar(name):
+			continue
+		case ws.TempSuffix != "" && strings.HasSuffix(name, ws.TempSuffix):
+			continue
+		case taken[name+".gz"]: // a raw copy, see KeepRaw
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, e := entry.Info()
+		if e != nil || path == opened {
+			continue
+		}
+		recs = append(recs, fileRecord{
+			name:    path,
+			created: info.ModTime(),
+			last:    info.ModTime(),
+			size:    info.Size(),
+			gzipped: strings.HasSuffix(name, ".gz"),
+		})
+	}
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].last.Before(recs[j].last) })
+	ws.files = recs
+}
+
+// rawCopy is the original of a compressed file kept alongside it, see KeepRaw
+type rawCopy struct {
+	rec fileRecord // as the compressed file's, but for the name
+	of  string     // name of the compressed file
+}
+
+// retainRaw deletes the raw copies chosen by RawRetention, independent of
+// what Retention does with the compressed files
+func (ws *WriteSplitter) retainRaw() error {
+	if ws.RawRetention == nil {
+		return nil
+	}
+
+	var done []FileInfo
+	for _, raw := range ws.raws {
+		if ws.finalized(raw.of) {
+			done = append(done, raw.rec.info())
+		}
+	}
+
+	var chosen []FileInfo
+	if e := guard("RawRetention", func() error {
+		chosen = ws.RawRetention.Evaluate(done)
+		return nil
+	}); e != nil {
+		return e
+	}
+
+	drop := make(map[string]bool)
+	for _, fi := range chosen {
+		drop[fi.Name] = true
+	}
+
+	var err error
+	keep := ws.raws[:0]
+	for _, raw := range ws.raws {
+		if drop[raw.rec.name] && ws.finalized(raw.of) {
+			e := os.Remove(raw.rec.name)
+			if e == nil || os.IsNotExist(e) {
+				continue
+			}
+			if err == nil {
+				err = e
+			}
+		}
+		keep = append(keep, raw)
+	}
+	ws.raws = keep
+	return err
+}
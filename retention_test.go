@@ -0,0 +1,89 @@
+package writesplitter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetentionAdoptsEarlierRuns(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"x-notes.txt", "xylophone", "config.yaml"} { // not ours, whatever their age
+		os.WriteFile(filepath.Join(dir, name), []byte("keep"), 0666)
+	}
+	for i := 0; i < 3; i++ { // three restarts, a file each
+		ws := LineSplitter(0, dir, "x")
+		ws.Retention = MaxFiles(2)
+		ws.Write([]byte("a\n"))
+		ws.Close()
+		time.Sleep(10 * time.Millisecond) // distinct modification times
+	}
+
+	ws := LineSplitter(0, dir, "x")
+	ws.Retention = MaxFiles(2)
+	ws.Write([]byte("a\n"))
+	ws.Rotate()
+	ws.Close()
+
+	names, _ := filepath.Glob(filepath.Join(dir, "x*"))
+	if len(names) != 5 { // the two most recent completed, the one left open by Rotate and the two not ours
+		t.Errorf("got %d files, want 5", len(names))
+	}
+	for _, name := range []string{"x-notes.txt", "xylophone", "config.yaml"} {
+		if _, e := os.Stat(filepath.Join(dir, name)); e != nil {
+			t.Errorf("%s: %v", name, e)
+		}
+	}
+}
+
+func TestRetentionNeedsPrefixToAdopt(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "2001-02-03T04:05:06Z")
+	os.WriteFile(old, []byte("someone else's"), 0666)
+
+	ws := LineSplitter(0, dir, "")
+	ws.Retention = MaxFiles(0)
+	ws.Write([]byte("a\n"))
+	ws.Rotate()
+	ws.Close()
+
+	if _, e := os.Stat(old); e != nil {
+		t.Errorf("adopted without a Prefix: %v", e)
+	}
+}
+
+func TestOurs(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(*WriteSplitter)
+		want bool
+	}{
+		{"x2026-10-16T12:00:00.123456789Z", nil, true},
+		{"x2026-10-16T12:00:00+02:00", nil, true},
+		{"x2026-10-16T12:00:00Z-3", nil, true},
+		{"x2026-10-16T12:00:00Z_10L_512B.gz", nil, true},
+		{"x2026-10-16T12:00:00Z-2_10L_512B.gz.sha256", func(ws *WriteSplitter) { ws.SumSuffix = ".sha256" }, true},
+		{"x2026-10-16T12:00:00Z.idx", nil, true},
+		{"x2026-10-16T12:00:00Z.tmp", func(ws *WriteSplitter) { ws.TempSuffix = ".tmp" }, true},
+		{"x20261016T120000.000000000Z-0000000042", func(ws *WriteSplitter) { ws.Sortable = true }, true},
+		{"x20261016T120000.000000000Z-0000000042-1.gz", func(ws *WriteSplitter) { ws.Sortable = true }, true},
+		{"x20261016T120000.000000000Z-42", func(ws *WriteSplitter) { ws.Sortable = true }, false},
+		{"x2026-10-16T12:00:00Z", func(ws *WriteSplitter) { ws.Sortable = true }, false},
+		{"x-earlier-run", nil, false},
+		{"x2026-10-16T12:00:00Z.log", nil, false},
+		{"x2026-10-16T12:00:00Z-a", nil, false},
+		{"x2026-10-16T12:00:00Z_10L", nil, false},
+		{"y2026-10-16T12:00:00Z", nil, false},
+		{"x2026-10-16T12:00:00Z", func(ws *WriteSplitter) { ws.Template = "{{.Prefix}}{{.Seq}}" }, false},
+	}
+	for _, tt := range tests {
+		ws := LineSplitter(0, "", "logs/x")
+		if tt.set != nil {
+			tt.set(ws)
+		}
+		if got := ws.ours(tt.name); got != tt.want {
+			t.Errorf("ours(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
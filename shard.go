@@ -0,0 +1,62 @@
+package writesplitter
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync/atomic"
+)
+
+// ErrNoShards is returned for a Sharded without any shards to write to
+var ErrNoShards = errors.New("WriteSplitter: no shards")
+
+// Sharded spreads writes across several WriteSplitters, each with its own file
+// and limits, for workloads where a single file is the bottleneck. Each Write
+// goes to exactly one shard: chosen by hashing the data if Hash is set,
+// round-robin otherwise.
+type Sharded struct {
+	Shards []*WriteSplitter
+	Hash   bool // route by an FNV-1a hash of each write instead of round-robin
+	next   uint64
+}
+
+// NewSharded returns a Sharded of n WriteSplitters built by fn, which is given
+// the index of the shard. Each shard needs its own Dir or Prefix. It returns
+// ErrNoShards if n is less than one.
+func NewSharded(n int, fn func(i int) *WriteSplitter) (*Sharded, error) {
+	if n < 1 {
+		return nil, ErrNoShards
+	}
+	s := &Sharded{Shards: make([]*WriteSplitter, n)}
+	for i := range s.Shards {
+		s.Shards[i] = fn(i)
+	}
+	return s, nil
+}
+
+// Write writes p to one of the shards. Shards are safe for concurrent use, so
+// Write is too.
+func (s *Sharded) Write(p []byte) (int, error) {
+	if len(s.Shards) == 0 {
+		return 0, ErrNoShards
+	}
+	var i uint64
+	if s.Hash {
+		h := fnv.New32a()
+		h.Write(p)
+		i = uint64(h.Sum32())
+	} else {
+		i = atomic.AddUint64(&s.next, 1) - 1
+	}
+	return s.Shards[i%uint64(len(s.Shards))].Write(p)
+}
+
+// Close closes every shard and returns the first error encountered
+func (s *Sharded) Close() error {
+	var err error
+	for _, ws := range s.Shards {
+		if e := ws.Close(); e != nil && e != ErrNotAFile && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,12 @@
+package writesplitter
+
+import "testing"
+
+func TestShardedWithoutShards(t *testing.T) {
+	if _, e := NewSharded(0, nil); e != ErrNoShards {
+		t.Errorf("got %v, want ErrNoShards", e)
+	}
+	if _, e := new(Sharded).Write([]byte("a")); e != ErrNoShards {
+		t.Errorf("got %v, want ErrNoShards", e)
+	}
+}
@@ -0,0 +1,82 @@
+package writesplitter
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// RotateOnSignal rotates ws every time one of sigs is received, SIGHUP if none
+// are given, so tools in the style of logrotate can ask for a new file without
+// restarting the process. Errors from Rotate are passed to OnError. Calling
+// the returned func stops the rotating. Where there is no SIGHUP, e.g. js,
+// nothing is watched for unless sigs are given.
+func (ws *WriteSplitter) RotateOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = rotateSignals
+	}
+	if len(sigs) == 0 { // Notify with no signals would relay all of them
+		return func() {}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				ws.report(ws.Rotate())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// CloseOnSignal closes ws when one of sigs is received, SIGTERM and SIGINT if
+// none are given, and then lets the signal take its course, usually ending
+// the process. Closing flushes Buffer, writes the Footer and waits for
+// background finalizes, so what was written just before shutdown makes it
+// into a completed file. An error from Close is passed to OnError. Calling the
+// returned func stops the watching; ws is left open.
+func (ws *WriteSplitter) CloseOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = closeSignals
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		select {
+		case sig := <-ch:
+			ws.report(ws.Close())
+			signal.Stop(ch)
+			p, e := os.FindProcess(os.Getpid())
+			if e == nil {
+				e = p.Signal(sig) // now handled as if ws never caught it
+			}
+			if e != nil {
+				os.Exit(1)
+			}
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
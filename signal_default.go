@@ -0,0 +1,14 @@
+//go:build !js && !plan9
+
+package writesplitter
+
+import (
+	"os"
+	"syscall"
+)
+
+// the signals RotateOnSignal and CloseOnSignal watch for if given none
+var (
+	rotateSignals = []os.Signal{syscall.SIGHUP}
+	closeSignals  = []os.Signal{syscall.SIGTERM, os.Interrupt}
+)
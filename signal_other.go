@@ -0,0 +1,12 @@
+//go:build js || plan9
+
+package writesplitter
+
+import "os"
+
+// there's no SIGHUP or SIGTERM here, so RotateOnSignal watches for nothing
+// unless given signals
+var (
+	rotateSignals []os.Signal
+	closeSignals  = []os.Signal{os.Interrupt}
+)
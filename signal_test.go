@@ -0,0 +1,124 @@
+//go:build unix
+
+package writesplitter
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// raise sends sig to the process and waits for it to have been handled
+func raise(t *testing.T, sig os.Signal) {
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, sig) // so it's never left to the default, which may end the test
+	defer signal.Stop(caught)
+	syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+	select {
+	case <-caught:
+	case <-time.After(time.Second):
+		t.Fatalf("%v never arrived", sig)
+	}
+	time.Sleep(20 * time.Millisecond) // for ws to act on it too
+}
+
+func TestRotateOnSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		signals int  // how many times SIGUSR1 is raised
+		stop    bool // stop watching first
+		closed  bool // close ws first
+		files   int
+	}{
+		{"once", 1, false, false, 2},
+		{"twice", 2, false, false, 3},
+		{"stopped", 1, true, false, 1},
+		{"closed", 1, false, true, 1},
+	}
+	for _, tt := range tests {
+		ws := LineSplitter(0, t.TempDir(), "x")
+		reported := make(chan error, 1)
+		ws.OnError = func(e error) {
+			select {
+			case reported <- e:
+			default:
+			}
+		}
+		ws.Write([]byte("a\n"))
+		stop := ws.RotateOnSignal(syscall.SIGUSR1)
+		if tt.stop {
+			stop()
+		}
+		if tt.closed {
+			ws.Close()
+		}
+		for i := 0; i < tt.signals; i++ {
+			raise(t, syscall.SIGUSR1)
+		}
+		stop()
+		ws.Close()
+
+		if n := ws.FilesCreated(); n != tt.files {
+			t.Errorf("%s: got %d files, want %d", tt.name, n, tt.files)
+		}
+		if tt.closed {
+			select {
+			case e := <-reported:
+				if e != ErrClosed {
+					t.Errorf("%s: reported %v, want ErrClosed", tt.name, e)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("%s: nothing reported", tt.name)
+			}
+		}
+	}
+}
+
+func TestCloseOnSignal(t *testing.T) {
+	held := make(chan os.Signal, 8) // catches what CloseOnSignal raises again once done
+	signal.Notify(held, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(held)
+
+	tests := []struct {
+		name   string
+		sig    os.Signal // raised once watching
+		stop   bool      // stop watching first
+		closed bool
+	}{
+		{"signalled", syscall.SIGUSR1, false, true},
+		{"other signal", syscall.SIGUSR2, false, false},
+		{"stopped", syscall.SIGUSR1, true, false},
+	}
+	for _, tt := range tests {
+		ws := LineSplitter(0, t.TempDir(), "x")
+		ws.Buffer = 64
+		ws.Footer = func(FileInfo) []byte { return []byte("end\n") }
+		ws.Write([]byte("a\n"))
+		name := ws.CurrentFile()
+		stop := ws.CloseOnSignal(syscall.SIGUSR1)
+		if tt.stop {
+			stop()
+		}
+		raise(t, tt.sig)
+		for i := 0; i < 50 && tt.closed && ws.CurrentFile() != ""; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		_, e := ws.Write([]byte("b\n"))
+		if tt.closed && e != ErrClosed {
+			t.Errorf("%s: Write returned %v, want ErrClosed", tt.name, e)
+		}
+		if !tt.closed && e != nil {
+			t.Errorf("%s: Write returned %v, want it left open", tt.name, e)
+		}
+		if tt.closed {
+			if b, _ := os.ReadFile(name); string(b) != "a\nend\n" {
+				t.Errorf("%s: got %q, want the buffered write and the footer", tt.name, b)
+			}
+		}
+		stop()
+		ws.Close()
+	}
+}
@@ -0,0 +1,23 @@
+package writesplitter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/henderjon/writesplitter"
+	"github.com/henderjon/writesplitter/splittertest"
+)
+
+func TestSoak(t *testing.T) {
+	tests := map[string]*writesplitter.WriteSplitter{
+		"lines":   writesplitter.LineSplitter(10, t.TempDir(), "x"),
+		"bytes":   writesplitter.ByteSplitter(256, t.TempDir(), "x"),
+		"precise": {Limit: 256, Bytes: true, Precise: true, Dir: t.TempDir()},
+		"async":   {Limit: 10, Async: true, Buffer: 128, Dir: t.TempDir()},
+	}
+	for name, ws := range tests {
+		if e := splittertest.Soak(ws, 200*time.Millisecond); e != nil {
+			t.Errorf("%s: %v", name, e)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package writesplitter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrSpillFull is returned for a write that is larger than a Spill's MaxBytes
+// on its own and so was dropped
+var ErrSpillFull = errors.New("WriteSplitter: write is larger than the spill can hold")
+
+// Spill writes to a remote Sink, e.g. a socket, spooling to local files while
+// the sink fails and replaying them, in order, once it works again. A write is
+// retried whole, so the sink may see one twice: delivery is at least once.
+//
+// MaxAge and MaxBytes bound the spooled backlog during a long outage. The
+// oldest writes are dropped to stay within them, see Dropped.
+type Spill struct {
+	MaxAge   time.Duration // if set, spooled writes older than this are dropped
+	MaxBytes int64         // if set, the oldest spooled writes are dropped to keep the total at most this
+
+	sink    io.Writer
+	local   *WriteSplitter // a SpoolSplitter, one file per spilled write
+	mu      sync.Mutex
+	pending []spilled // spilled writes not yet replayed, oldest first
+	size    int64     // total size of pending
+	dropped int       // writes dropped to stay within MaxAge and MaxBytes
+	lost    int64     // bytes dropped
+	last    string    // the file local wrote last
+}
+
+// spilled is a write spooled to a file of its own
+type spilled struct {
+	name string
+	size int64
+	at   time.Time
+}
+
+// NewSpill returns a Spill that writes to sink, spooling to files in dir
+// named with prefix while sink fails. Writes spooled by an earlier Spill with
+// the same dir and prefix, e.g. before a restart, are pending from the start.
+func NewSpill(sink io.Writer, dir, prefix string) *Spill {
+	s := &Spill{sink: sink, local: SpoolSplitter(dir, prefix)}
+	s.local.Sortable = true // so the files left behind can be put back in order
+	s.local.OnRotate = func(name, _ string) { s.last = name }
+	s.reload()
+	return s
+}
+
+// reload takes the spooled writes found on disk as pending, in the order
+// their names sort in, which is the order they were spooled in
+func (s *Spill) reload() {
+	entries, e := os.ReadDir(orDot(s.local.Dir)) // sorted by name already
+	if e != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !s.local.ours(entry.Name()) {
+			continue
+		}
+		info, e := entry.Info()
+		if e != nil {
+			continue
+		}
+		s.pending = append(s.pending, spilled{filepath.Join(s.local.Dir, entry.Name()), info.Size(), info.ModTime()})
+		s.size += info.Size()
+	}
+}
+
+// Write writes p to the sink, or spools it if the sink fails or there are
+// spooled writes it hasn't caught up with yet
+func (s *Spill) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 || s.replay() == nil {
+		if _, e := writeAll(s.sink, p); e == nil {
+			return len(p), nil
+		}
+	}
+	return s.spill(p)
+}
+
+// Replay writes spooled writes to the sink, stopping at the first failure,
+// and returns that failure. Write replays on its own, Replay is for catching
+// up while nothing is being written.
+func (s *Spill) Replay() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replay()
+}
+
+// Pending returns how many spooled writes are waiting to be replayed
+func (s *Spill) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// Dropped returns how many writes, and bytes, have been dropped to keep the
+// backlog within MaxAge and MaxBytes
+func (s *Spill) Dropped() (writes int, bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped, s.lost
+}
+
+// Close closes the local WriteSplitter. Writes still pending stay on disk.
+func (s *Spill) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.local.Close()
+}
+
+// spill writes p to a file of its own, first dropping the oldest writes as
+// needed to make room for it
+func (s *Spill) spill(p []byte) (int, error) {
+	if s.MaxBytes > 0 && int64(len(p)) > s.MaxBytes {
+		s.dropped++
+		s.lost += int64(len(p))
+		return 0, ErrSpillFull
+	}
+	s.evict(int64(len(p)))
+
+	s.last = ""
+	n, e := s.local.Write(p)
+	if e != nil {
+		return n, e
+	}
+	s.local.forget(s.last) // pending keeps track, local needn't remember every file
+	s.pending = append(s.pending, spilled{s.last, int64(n), time.Now()})
+	s.size += int64(n)
+	return n, nil
+}
+
+// evict drops spooled writes that are older than MaxAge or, oldest first, that
+// don't leave room under MaxBytes for another extra bytes
+func (s *Spill) evict(extra int64) {
+	for len(s.pending) > 0 {
+		old := s.pending[0]
+		tooOld := s.MaxAge > 0 && time.Since(old.at) > s.MaxAge
+		tooBig := s.MaxBytes > 0 && s.size+extra > s.MaxBytes
+		if !tooOld && !tooBig {
+			return
+		}
+		os.Remove(old.name)
+		s.pop()
+		s.dropped++
+		s.lost += old.size
+	}
+}
+
+// pop forgets the oldest spooled write
+func (s *Spill) pop() {
+	s.size -= s.pending[0].size
+	s.pending = s.pending[1:]
+}
+
+// replay is Replay without the locking
+func (s *Spill) replay() error {
+	s.evict(0)
+	for len(s.pending) > 0 {
+		b, e := os.ReadFile(s.pending[0].name)
+		if e == nil {
+			_, e = writeAll(s.sink, b)
+		}
+		if e != nil && !os.IsNotExist(e) { // a file removed by hand can't be replayed
+			return e
+		}
+		os.Remove(s.pending[0].name)
+		s.pop()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package writesplitter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// flaky is a sink that fails while down
+type flaky struct {
+	down bool
+	bytes.Buffer
+}
+
+func (f *flaky) Write(p []byte) (int, error) {
+	if f.down {
+		return 0, errors.New("down")
+	}
+	return f.Buffer.Write(p)
+}
+
+func TestSpillReplays(t *testing.T) {
+	dir := t.TempDir()
+	sink := &flaky{down: true}
+	s := NewSpill(sink, dir, "s")
+	s.MaxBytes = 4
+	for _, w := range []string{"a", "b", "c", "d", "e", "f"} {
+		s.Write([]byte(w))
+	}
+	if n := s.Pending(); n != 4 {
+		t.Errorf("got %d pending, want 4", n)
+	}
+	if n := len(s.local.Files()); n != 0 {
+		t.Errorf("the local splitter remembers %d files", n)
+	}
+
+	sink.down = false
+	if e := s.Replay(); e != nil {
+		t.Fatal(e)
+	}
+	if got := sink.String(); got != "cdef" {
+		t.Errorf("replayed %q, want cdef", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d spooled files left", len(entries))
+	}
+	if e := s.Close(); e != nil {
+		t.Error(e)
+	}
+}
+
+func TestSpillRecovers(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not spooled"), 0666)
+	sink := &flaky{down: true}
+	s := NewSpill(sink, dir, "s")
+	for _, w := range []string{"a", "b", "c"} {
+		s.Write([]byte(w))
+	}
+	s.Close() // a restart
+
+	sink.down = false
+	s = NewSpill(sink, dir, "s")
+	if n := s.Pending(); n != 3 {
+		t.Errorf("got %d pending, want 3", n)
+	}
+	s.Write([]byte("d"))
+	if got := sink.String(); got != "abcd" {
+		t.Errorf("got %q, want abcd", got)
+	}
+	s.Close()
+}
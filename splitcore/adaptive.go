@@ -0,0 +1,87 @@
+package splitcore
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptivePolicy is a RotationPolicy whose byte limit follows the observed
+// throughput, aiming for chunks that each take Target to write while keeping
+// them between Min and Max bytes, see Adaptive. It is safe for concurrent use
+// but follows a single series of chunks, so splitters sharing a configuration
+// each need a Fork of it.
+type AdaptivePolicy struct {
+	Target time.Duration // how long each chunk should take to write
+	Min    int           // the limit never goes below this many bytes
+	Max    int           // nor above this many, which is also the limit until there's a rate to go on
+
+	mu     sync.Mutex
+	rate   float64   // bytes per second, smoothed over the chunks seen
+	opened time.Time // when the chunk last seen began
+	bytes  int       // bytes last seen written to it
+	seen   time.Time // when it was last seen
+}
+
+// Adaptive returns an AdaptivePolicy aiming for chunks that take target to
+// write, of between min and max bytes
+func Adaptive(target time.Duration, min, max int) *AdaptivePolicy {
+	return &AdaptivePolicy{Target: target, Min: min, Max: max}
+}
+
+// ShouldRotate reports whether s has reached the current limit. The first
+// time it sees a new chunk, the throughput of the previous one is taken into
+// account.
+func (a *AdaptivePolicy) ShouldRotate(s State) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if !s.Opened.Equal(a.opened) {
+		a.observe()
+		a.opened = s.Opened
+	}
+	a.bytes, a.seen = s.Bytes, now
+	return s.Bytes >= a.limit()
+}
+
+// Fork returns an AdaptivePolicy with the same Target, Min and Max that has
+// yet to see any chunks
+func (a *AdaptivePolicy) Fork() RotationPolicy {
+	return Adaptive(a.Target, a.Min, a.Max)
+}
+
+// Limit returns the byte limit currently in effect
+func (a *AdaptivePolicy) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.limit()
+}
+
+// observe folds the throughput of the chunk last seen into rate
+func (a *AdaptivePolicy) observe() {
+	elapsed := a.seen.Sub(a.opened).Seconds()
+	if a.opened.IsZero() || elapsed <= 0 {
+		return
+	}
+	rate := float64(a.bytes) / elapsed
+	if a.rate == 0 {
+		a.rate = rate
+		return
+	}
+	a.rate = (a.rate + rate) / 2 // recent chunks count most
+}
+
+// limit is Limit without the locking
+func (a *AdaptivePolicy) limit() int {
+	if a.rate == 0 {
+		return a.Max
+	}
+	limit := int(a.rate * a.Target.Seconds())
+	switch {
+	case limit < a.Min:
+		return a.Min
+	case limit > a.Max:
+		return a.Max
+	}
+	return limit
+}
@@ -0,0 +1,121 @@
+package splitcore
+
+import "time"
+
+// RotationPolicy decides, given the accounting for the current chunk, whether
+// the next chunk should begin before the next write. Policy is one, combining
+// a line or byte Limit with an Interval; the funcs below build others, e.g.
+//
+//	Any(Bytes(100<<20), Age(time.Hour))
+//
+// for 100MiB or an hour, whichever comes first.
+type RotationPolicy interface {
+	ShouldRotate(s State) bool
+}
+
+// Forker is implemented by RotationPolicies that follow a single series of
+// chunks, e.g. AdaptivePolicy. Fork returns a policy configured the same but
+// with none of the state, for another series; splitters that copy their
+// configuration, such as the Streams of a WriteSplitter, fork their Rotation.
+type Forker interface {
+	Fork() RotationPolicy
+}
+
+// Fork returns p forked if it is a Forker, or p itself, which holds no state
+func Fork(p RotationPolicy) RotationPolicy {
+	if f, ok := p.(Forker); ok {
+		return f.Fork()
+	}
+	return p
+}
+
+// RotationFunc is an adapter to allow the use of ordinary functions as a
+// RotationPolicy
+type RotationFunc func(s State) bool
+
+// ShouldRotate calls fn(s)
+func (fn RotationFunc) ShouldRotate(s State) bool {
+	return fn(s)
+}
+
+// ShouldRotate is Due as of now
+func (p Policy) ShouldRotate(s State) bool {
+	return p.Due(s, time.Now())
+}
+
+// Lines returns a RotationPolicy that rotates once n lines have been written
+func Lines(n int) RotationPolicy {
+	return RotationFunc(func(s State) bool { return s.Lines >= n })
+}
+
+// Bytes returns a RotationPolicy that rotates once n bytes have been written
+func Bytes(n int) RotationPolicy {
+	return RotationFunc(func(s State) bool { return s.Bytes >= n })
+}
+
+// Age returns a RotationPolicy that rotates once a chunk is d old
+func Age(d time.Duration) RotationPolicy {
+	return RotationFunc(func(s State) bool { return time.Since(s.Opened) >= d })
+}
+
+// At returns a RotationPolicy that rotates once the time next returns for the
+// chunk has come, see Policy.Next
+func At(next func(opened time.Time) time.Time) RotationPolicy {
+	return RotationFunc(func(s State) bool { return !time.Now().Before(next(s.Opened)) })
+}
+
+// Any returns a RotationPolicy that rotates as soon as any of policies would
+func Any(policies ...RotationPolicy) RotationPolicy {
+	return anyPolicy(policies)
+}
+
+// All returns a RotationPolicy that rotates only once all of policies would,
+// e.g. to keep files from rotating on age alone while they're still small
+func All(policies ...RotationPolicy) RotationPolicy {
+	return allPolicy(policies)
+}
+
+// anyPolicy is the RotationPolicy of Any
+type anyPolicy []RotationPolicy
+
+// ShouldRotate reports whether any of the policies would rotate
+func (ps anyPolicy) ShouldRotate(s State) bool {
+	for _, p := range ps {
+		if p.ShouldRotate(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fork forks each of the policies
+func (ps anyPolicy) Fork() RotationPolicy {
+	return anyPolicy(forkAll(ps))
+}
+
+// allPolicy is the RotationPolicy of All
+type allPolicy []RotationPolicy
+
+// ShouldRotate reports whether all of the policies would rotate
+func (ps allPolicy) ShouldRotate(s State) bool {
+	for _, p := range ps {
+		if !p.ShouldRotate(s) {
+			return false
+		}
+	}
+	return len(ps) > 0
+}
+
+// Fork forks each of the policies
+func (ps allPolicy) Fork() RotationPolicy {
+	return allPolicy(forkAll(ps))
+}
+
+// forkAll returns policies, each forked
+func forkAll(policies []RotationPolicy) []RotationPolicy {
+	forked := make([]RotationPolicy, len(policies))
+	for i, p := range policies {
+		forked[i] = Fork(p)
+	}
+	return forked
+}
@@ -0,0 +1,145 @@
+// Package splitcore is the accounting behind writesplitter without any of the
+// file IO: it counts what has been written to the current chunk, decides when
+// the next chunk should begin and names chunks. It can be used to split any
+// stream of writes, e.g. into network messages or object uploads.
+package splitcore
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// Policy says when a chunk is complete. The zero Policy never completes one.
+type Policy struct {
+	Limit    int           // lines or bytes per chunk, zero for no limit
+	Bytes    bool          // Limit counts bytes rather than lines
+	Newlines bool          // count the '\n' in each write as lines, rather than one line per write
+	Interval time.Duration // how long a chunk is written to, zero for no limit
+
+	// Next, if set, returns when a chunk begun at opened is complete in place
+	// of adding Interval, e.g. for schedules that smear leap seconds
+	Next func(opened time.Time) time.Time
+}
+
+// State is the accounting for the current chunk
+type State struct {
+	Lines  int       // lines written, see Policy.Newlines
+	Bytes  int       // bytes written
+	Opened time.Time // when the chunk began
+}
+
+// Start resets s for a chunk that began at now
+func (s *State) Start(now time.Time) {
+	*s = State{Opened: now}
+}
+
+// Add records a write of n bytes that counts as the given number of lines
+func (s *State) Add(n, lines int) {
+	s.Bytes += n
+	s.Lines += lines
+}
+
+// Lines returns how many lines a write of b counts as
+func (p Policy) Lines(b []byte) int {
+	if p.Newlines {
+		return bytes.Count(b, []byte{'\n'})
+	}
+	return 1
+}
+
+// Count returns whichever of s.Lines or s.Bytes Limit applies to
+func (p Policy) Count(s State) int {
+	if p.Bytes {
+		return s.Bytes
+	}
+	return s.Lines
+}
+
+// Due reports whether a new chunk should begin before the next write. As the
+// size of the next write isn't taken into account, a chunk may run over Limit
+// by up to one write.
+func (p Policy) Due(s State, now time.Time) bool {
+	switch {
+	case p.Limit > 0 && p.Bytes && s.Bytes >= p.Limit:
+		return true
+	case p.Limit > 0 && s.Lines >= p.Limit:
+		return true
+	case p.Next != nil || p.Interval > 0:
+		return !now.Before(p.Boundary(s.Opened))
+	}
+	return false
+}
+
+// Boundary returns when a chunk begun at opened is complete by time, the zero
+// Time if never
+func (p Policy) Boundary(opened time.Time) time.Time {
+	switch {
+	case p.Next != nil:
+		return p.Next(opened)
+	case p.Interval > 0:
+		return opened.Add(p.Interval)
+	}
+	return time.Time{}
+}
+
+// Aligned returns a Policy.Next that completes chunks on the multiples of d
+// since the zero Time, e.g. on the hour for time.Hour, rather than d after
+// each began
+func Aligned(d time.Duration) func(time.Time) time.Time {
+	return func(opened time.Time) time.Time {
+		return opened.Truncate(d).Add(d)
+	}
+}
+
+// Reached reports whether s has reached the given fraction of Limit, e.g. 0.9
+// for a chunk that is nearly complete. It is always false without a Limit.
+func (p Policy) Reached(s State, frac float64) bool {
+	return p.Limit > 0 && float64(p.Count(s)) >= frac*float64(p.Limit)
+}
+
+// AppendName appends the default name of a chunk begun at t, prefix followed
+// by t in RFC 3339 with nanoseconds, to dst and returns the result
+func AppendName(dst []byte, prefix string, t time.Time) []byte {
+	dst = append(dst, prefix...)
+	return t.AppendFormat(dst, time.RFC3339Nano)
+}
+
+// Cut returns how much of b fits in the current chunk without it exceeding a
+// byte Limit, which is len(b) if all of it does or Limit doesn't count bytes.
+// With newline set, the cut is moved back to just after the last '\n' that
+// fits, if there is one.
+func (p Policy) Cut(s State, b []byte, newline bool) int {
+	room := p.Limit - s.Bytes
+	if !p.Bytes || p.Limit <= 0 || len(b) <= room {
+		return len(b)
+	}
+	if room <= 0 {
+		return 0
+	}
+	if newline {
+		if i := bytes.LastIndexByte(b[:room], '\n'); i >= 0 {
+			return i + 1
+		}
+	}
+	return room
+}
+
+// SortableLayout is the time layout of AppendSortableName: fixed width, UTC
+// and free of characters that are special to shells or filesystems
+const SortableLayout = "20060102T150405.000000000Z"
+
+// AppendSortableName appends a name for the seq-th chunk, begun at t, that
+// sorts correctly by plain byte comparison: prefix, t in UTC per
+// SortableLayout, a dash and seq zero-padded to 10 digits
+func AppendSortableName(dst []byte, prefix string, t time.Time, seq int) []byte {
+	dst = append(dst, prefix...)
+	dst = t.UTC().AppendFormat(dst, SortableLayout)
+	dst = append(dst, '-')
+	var digits [20]byte
+	d := strconv.AppendInt(digits[:0], int64(seq), 10)
+	for i := len(d); i < 10; i++ {
+		dst = append(dst, '0')
+	}
+	return append(dst, d...)
+}
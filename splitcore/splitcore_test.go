@@ -0,0 +1,90 @@
+package splitcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendName(t *testing.T) {
+	now := time.Now()
+	if got, want := string(AppendName(nil, "x", now)), "x"+now.Format(time.RFC3339Nano); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAppendSortableName(t *testing.T) {
+	early := time.Date(2024, 1, 1, 0, 0, 0, 5, time.UTC)
+	late := time.Date(2024, 1, 1, 0, 0, 0, 40, time.UTC)
+	a := string(AppendSortableName(nil, "x", early, 9))
+	b := string(AppendSortableName(nil, "x", late, 10))
+	if a >= b {
+		t.Errorf("%q doesn't sort before %q", a, b)
+	}
+}
+
+func TestAligned(t *testing.T) {
+	opened := time.Date(2024, 1, 1, 10, 42, 0, 0, time.UTC)
+	if got, want := Aligned(time.Hour)(opened), time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPolicyNext(t *testing.T) {
+	opened := time.Date(2024, 1, 1, 10, 42, 0, 0, time.UTC)
+	p := Policy{Next: Aligned(time.Hour)}
+	s := State{Opened: opened}
+	if p.Due(s, opened.Add(17*time.Minute)) {
+		t.Error("due before the boundary")
+	}
+	if !p.Due(s, opened.Add(18*time.Minute)) {
+		t.Error("not due at the boundary")
+	}
+}
+
+var sink string
+
+// BenchmarkFormat is what naming a file cost before AppendName
+func BenchmarkFormat(b *testing.B) {
+	now := time.Now()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sink = "prefix-" + now.Format(time.RFC3339Nano)
+	}
+}
+
+func BenchmarkAppendName(b *testing.B) {
+	now := time.Now()
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendName(buf[:0], "prefix-", now)
+		sink = string(buf)
+	}
+}
+
+func BenchmarkAppendSortableName(b *testing.B) {
+	now := time.Now()
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendSortableName(buf[:0], "prefix-", now, i)
+		sink = string(buf)
+	}
+}
+
+func TestFork(t *testing.T) {
+	a := Adaptive(time.Second, 10, 1000)
+	a.rate = 50 // as if it had seen chunks, for a limit of 50
+
+	forked := Fork(Any(Lines(5), All(a))).(anyPolicy)
+	fa := forked[1].(allPolicy)[0].(*AdaptivePolicy)
+	if fa == a {
+		t.Fatal("the AdaptivePolicy is shared")
+	}
+	if fa.Limit() != 1000 || a.Limit() != 50 {
+		t.Errorf("got limits %d and %d, want a fresh 1000 and the original 50", fa.Limit(), a.Limit())
+	}
+	if p := Lines(5); Fork(p) == nil || Fork(nil) != nil {
+		t.Error("stateless policies aren't passed through")
+	}
+}
@@ -0,0 +1,66 @@
+package splittertest
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// MemFactory is a writesplitter.FileFactory that keeps files in memory, so a
+// WriteSplitter can be tested without touching disk. The zero MemFactory is
+// ready to use and safe for concurrent use.
+type MemFactory struct {
+	mu    sync.Mutex
+	names []string
+	files map[string]*bytes.Buffer
+}
+
+// Create makes an empty file called name, replacing any file of that name
+func (m *MemFactory) Create(name string) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.files == nil {
+		m.files = make(map[string]*bytes.Buffer)
+	}
+	if _, ok := m.files[name]; !ok {
+		m.names = append(m.names, name)
+	}
+	m.files[name] = new(bytes.Buffer)
+	return &memFile{m: m, buf: m.files[name]}, nil
+}
+
+// Names returns the name of every file created, in the order they were created
+func (m *MemFactory) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.names...)
+}
+
+// Bytes returns a copy of what was written to the named file
+func (m *MemFactory) Bytes(name string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buf, ok := m.files[name]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), buf.Bytes()...), true
+}
+
+// memFile is a file created by a MemFactory
+type memFile struct {
+	m   *MemFactory
+	buf *bytes.Buffer
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	return f.buf.Write(p)
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
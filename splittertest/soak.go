@@ -0,0 +1,241 @@
+// Package splittertest provides tools for testing code built on writesplitter.
+package splittertest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henderjon/writesplitter"
+)
+
+const (
+	writers   = 8  // goroutines writing concurrently
+	failEvery = 50 // on average, one in this many new files fails to be created
+)
+
+// errInjected is the failure Soak injects when files are created
+var errInjected = errors.New("splittertest: injected failure")
+
+// Violation is an invariant Soak found broken
+type Violation struct {
+	Invariant string
+	Detail    string
+}
+
+func (v *Violation) Error() string {
+	return "splittertest: " + v.Invariant + ": " + v.Detail
+}
+
+// Soak drives ws with randomized concurrent writes, rotations and injected
+// failures to create files for duration, closes it and then checks that:
+//
+//   - every record whose Write succeeded is in the files exactly once
+//   - records from each writer appear in the order they were written, reading
+//     the files in the order ws.Files returns them
+//   - with a byte Limit, no file is larger than it should be: Limit if Precise
+//     is set, otherwise Limit plus the size of one record
+//
+// Failures are injected by making OnCreate fail now and then, after calling
+// any OnCreate ws already has. ws must have a Dir and must not be Ephemeral or
+// have a RouteKey, Retention or WarmDir, all of which move records out of
+// ws.Files. Errors from ws other than the injected ones are returned as is,
+// broken invariants as a *Violation.
+func Soak(ws *writesplitter.WriteSplitter, duration time.Duration) error {
+	switch {
+	case ws.Dir == "":
+		return errors.New("splittertest: Soak requires a Dir")
+	case ws.Ephemeral, ws.RouteKey != nil, ws.Retention != nil, ws.WarmDir != "":
+		return errors.New("splittertest: Soak can't account for files that are moved or removed")
+	}
+
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	intn := func(n int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return rnd.Intn(n)
+	}
+
+	onCreate := ws.OnCreate
+	ws.OnCreate = func(f *os.File) error {
+		if onCreate != nil {
+			if e := onCreate(f); e != nil {
+				return e
+			}
+		}
+		if intn(failEvery) == 0 {
+			return errInjected
+		}
+		return nil
+	}
+
+	done := make(chan struct{})
+	time.AfterFunc(duration, func() { close(done) })
+
+	var wg sync.WaitGroup
+	wrote := make([]int, writers)           // records attempted, by writer
+	failed := make([]map[int]bool, writers) // records whose Write failed, by writer
+	for w := range failed {
+		failed[w] = make(map[int]bool)
+	}
+	errs := make([]error, writers+1)
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for seq := 0; ; seq++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				_, e := ws.Write(record(w, seq))
+				wrote[w]++
+				switch {
+				case e == nil:
+				case errors.Is(e, errInjected):
+					failed[w][seq] = true
+				default:
+					errs[w] = e
+					return
+				}
+				if intn(4) == 0 {
+					time.Sleep(time.Duration(intn(100)) * time.Microsecond)
+				}
+			}
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Duration(1+intn(20)) * time.Millisecond):
+			}
+			if e := ws.Rotate(); e != nil && !errors.Is(e, errInjected) {
+				errs[writers] = e
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	if e := ws.Close(); e != nil && !errors.Is(e, errInjected) && e != writesplitter.ErrNotAFile {
+		return e
+	}
+	for _, e := range errs {
+		if e != nil {
+			return e
+		}
+	}
+
+	files := ws.Files()
+	if e := checkSizes(ws, files); e != nil {
+		return e
+	}
+	return checkRecords(files, wrote, failed)
+}
+
+// recordLen is the length of every record, see record
+const recordLen = len("soak 0000 000000000000 .\n")
+
+// record returns the seq-th record of writer w. Records have a fixed length
+// and end in " ." so that what's left of a Write torn by a failure can be told
+// apart from a record.
+func record(w, seq int) []byte {
+	return []byte(fmt.Sprintf("soak %04d %012d .\n", w, seq))
+}
+
+// parse returns the writer and sequence number of the last record in line,
+// which may follow what's left of a torn Write
+func parse(line string) (w, seq int, ok bool) {
+	i := strings.LastIndex(line, "soak ")
+	if i < 0 || len(line)-i != recordLen-1 {
+		return 0, 0, false
+	}
+	var end string
+	n, _ := fmt.Sscanf(line[i:], "soak %d %d %s", &w, &seq, &end)
+	return w, seq, n == 3 && end == "." && w >= 0 && w < writers
+}
+
+// checkSizes checks no file is larger than a byte Limit allows
+func checkSizes(ws *writesplitter.WriteSplitter, files []string) error {
+	if !ws.Bytes || ws.Limit <= 0 || ws.Compress || ws.Header != nil || ws.Footer != nil {
+		return nil
+	}
+	max := int64(ws.Limit)
+	if !ws.Precise {
+		max += int64(recordLen)
+	}
+	for _, name := range files {
+		fi, e := os.Stat(name)
+		if os.IsNotExist(e) && ws.RemoveEmpty {
+			continue
+		}
+		if e != nil {
+			return e
+		}
+		if fi.Size() > max {
+			return &Violation{"size", fmt.Sprintf("%s is %d bytes, more than %d", name, fi.Size(), max)}
+		}
+	}
+	return nil
+}
+
+// checkRecords reads files back and checks every acknowledged record is there
+// once and in order. Records whose Write failed may or may not be there.
+func checkRecords(files []string, wrote []int, failed []map[int]bool) error {
+	var present []string
+	for _, name := range files {
+		if _, e := os.Stat(name); e == nil {
+			present = append(present, name)
+		}
+	}
+
+	rr := writesplitter.NewRecordReader(present...)
+	defer rr.Close()
+
+	next := make([]int, writers) // lowest sequence number each writer may have next
+	seen := make([]map[int]bool, writers)
+	for w := range seen {
+		seen[w] = make(map[int]bool)
+	}
+
+	for {
+		line, e := rr.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return e
+		}
+		w, seq, ok := parse(string(line))
+		if !ok {
+			continue // a header, footer or torn Write
+		}
+		if seq < next[w] {
+			return &Violation{"order", fmt.Sprintf("writer %d record %d follows record %d", w, seq, next[w]-1)}
+		}
+		next[w] = seq + 1
+		seen[w][seq] = true
+	}
+
+	for w, n := range wrote {
+		for seq := 0; seq < n; seq++ {
+			if !seen[w][seq] && !failed[w][seq] {
+				return &Violation{"lost", fmt.Sprintf("writer %d record %d is missing", w, seq)}
+			}
+		}
+	}
+	return nil
+}
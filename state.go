@@ -0,0 +1,74 @@
+package writesplitter
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// State is the part of a WriteSplitter's history that outlives its process:
+// the files it completed, how many it has created, which NameData.Seq
+// continues from, and how many records it has written, which Sequence
+// continues from. It can be encoded, e.g. with encoding/json, to carry a
+// series over to another host or process, see Export and Import.
+type State struct {
+	Seq     int        // files created so far
+	Records uint64     // records written so far, see WriteSplitter.Sequence
+	Files   []FileInfo // completed files, oldest first
+}
+
+// Export returns the State of ws. The open file and any file still being
+// finalized are left out.
+func (ws *WriteSplitter) Export() State {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	st := State{Seq: ws.seq, Records: ws.records}
+	for _, rec := range ws.files {
+		if ws.finalized(rec.name) {
+			st.Files = append(st.Files, rec.info())
+		}
+	}
+	return st
+}
+
+// Import takes on st as its own, so Files, Retention, FilesBetween and the
+// like include the files of st and Seq carries on from it. It must be called
+// before the first Write and returns ErrInUse otherwise.
+func (ws *WriteSplitter) Import(st State) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.handle != nil || len(ws.files) > 0 {
+		return ErrInUse
+	}
+
+	ws.seq, ws.records = st.Seq, st.Records
+	for _, fi := range st.Files {
+		ws.files = append(ws.files, fileRecord{
+			name:    fi.Name,
+			created: fi.Created,
+			first:   fi.First,
+			last:    fi.Last,
+			size:    fi.Size,
+			lines:   fi.Lines,
+			digest:  fi.Digest,
+			meta:    copyMeta(fi.Meta),
+			gzipped: strings.HasSuffix(fi.Name, ".gz"),
+		})
+	}
+	return nil
+}
+
+// Move returns a copy of st for files that were moved from dir from to dir
+// to, e.g. when carrying a series over to another host. Files outside from
+// are left as they are.
+func (st State) Move(from, to string) State {
+	moved := State{Seq: st.Seq, Records: st.Records, Files: make([]FileInfo, len(st.Files))}
+	for i, fi := range st.Files {
+		if rel, e := filepath.Rel(from, fi.Name); e == nil && !strings.HasPrefix(rel, "..") {
+			fi.Name = filepath.Join(to, rel)
+		}
+		moved.Files[i] = fi
+	}
+	return moved
+}
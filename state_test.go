@@ -0,0 +1,82 @@
+package writesplitter
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes int  // lines written, one file each
+		close  bool // close before Export
+		used   bool // write to the importing WriteSplitter first
+		files  int  // files in the State
+		err    error
+	}{
+		{"nothing written", 0, true, false, 0, nil},
+		{"completed", 2, true, false, 2, nil},
+		{"open file left out", 2, false, false, 1, nil},
+		{"in use", 2, true, true, 2, ErrInUse},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		ws := LineSplitter(1, dir, "x")
+		for i := 0; i < tt.writes; i++ {
+			ws.Write([]byte("a\n"))
+			ws.SetFileMeta("job", "7")
+		}
+		if tt.close {
+			ws.Close()
+		}
+		st := ws.Export()
+		ws.Close()
+		if len(st.Files) != tt.files || st.Seq != tt.writes {
+			t.Errorf("%s: exported %d files at Seq %d, want %d at %d", tt.name, len(st.Files), st.Seq, tt.files, tt.writes)
+		}
+
+		b, e := json.Marshal(st) // as if carried to another host
+		if e != nil {
+			t.Fatal(e)
+		}
+		var carried State
+		if e = json.Unmarshal(b, &carried); e != nil {
+			t.Fatal(e)
+		}
+
+		next := LineSplitter(1, dir, "x")
+		if tt.used {
+			next.Write([]byte("b\n"))
+		}
+		if e := next.Import(carried); e != tt.err {
+			t.Errorf("%s: Import returned %v, want %v", tt.name, e, tt.err)
+		}
+		next.Close()
+		if tt.err != nil {
+			continue
+		}
+		if got := next.Export(); !reflect.DeepEqual(got, carried) {
+			t.Errorf("%s: got %+v after Import, want %+v", tt.name, got, carried)
+		}
+	}
+}
+
+func TestStateMove(t *testing.T) {
+	st := State{Seq: 2, Files: []FileInfo{
+		{Name: filepath.Join("from", "a")},
+		{Name: filepath.Join("from", "sub", "b")},
+		{Name: filepath.Join("elsewhere", "c")},
+	}}
+	moved := st.Move("from", "to")
+	want := []string{filepath.Join("to", "a"), filepath.Join("to", "sub", "b"), filepath.Join("elsewhere", "c")}
+	for i, fi := range moved.Files {
+		if fi.Name != want[i] {
+			t.Errorf("got %q, want %q", fi.Name, want[i])
+		}
+	}
+	if st.Files[0].Name != filepath.Join("from", "a") || moved.Seq != st.Seq {
+		t.Error("Move changed st or lost its Seq")
+	}
+}
@@ -0,0 +1,138 @@
+package writesplitter
+
+import (
+	"io"
+
+	"github.com/henderjon/writesplitter/splitcore"
+)
+
+// Stream returns the named sub-stream of ws, creating it on first use. A
+// stream is a WriteSplitter of its own, configured like ws, whose files are
+// prefixed with Prefix + name + "-". Streams rotate whenever ws rotates, in
+// addition to rotating at their own Limit, or their own Fork of Rotation, and
+// are closed when ws is closed. This is meant for writing e.g. stdout and stderr of a process side by side.
+func (ws *WriteSplitter) Stream(name string) io.Writer {
+	return ws.stream(name)
+}
+
+// stream is Stream, returning the stream itself
+func (ws *WriteSplitter) stream(name string) *WriteSplitter {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if s, ok := ws.streams[name]; ok {
+		return s
+	}
+
+	s := ws.clone()
+	s.Prefix = ws.Prefix + name + "-"
+	s.RouteKey = nil // a stream writes everything it is given
+	s.Link = ""      // the link follows ws itself
+	if ws.MaxTotalBytes > 0 {
+		ws.share(s) // one budget for all
+	}
+	if ws.streams == nil {
+		ws.streams = make(map[string]*WriteSplitter)
+	}
+	ws.streams[name] = s
+	return s
+}
+
+// rotateStreams rotates every stream that has a file open
+func (ws *WriteSplitter) rotateStreams() {
+	for _, s := range ws.streams {
+		s.mu.Lock()
+		if s.current != "" {
+			s.ctx = ws.ctx // the call rotating ws rotates s too
+			s.rotate()
+			s.ctx = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// closeStreams closes every stream and returns the first error encountered
+func (ws *WriteSplitter) closeStreams() error {
+	var err error
+	for _, s := range ws.streams {
+		if e := s.Close(); e != nil && e != ErrNotAFile && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// clone returns a new WriteSplitter with the same configuration as ws
+func (ws *WriteSplitter) clone() *WriteSplitter {
+	return &WriteSplitter{
+		Limit:       ws.Limit,
+		Interval:    ws.Interval,
+		Dir:         ws.Dir,
+		Prefix:      ws.Prefix,
+		Template:    ws.Template,
+		Bytes:       ws.Bytes,
+		Newlines:    ws.Newlines,
+		Ephemeral:   ws.Ephemeral,
+		PreOpen:     ws.PreOpen,
+		Async:       ws.Async,
+		RemoveEmpty: ws.RemoveEmpty,
+		PerWrite:    ws.PerWrite,
+		AppendOnly:  ws.AppendOnly,
+		DropCache:   ws.DropCache,
+		Strict:      ws.Strict,
+		Compress:    ws.Compress,
+		KeepRaw:     ws.KeepRaw,
+		SizeInName:  ws.SizeInName,
+		Link:        ws.Link,
+		Buffer:      ws.Buffer,
+		FlushEvery:  ws.FlushEvery,
+		Resume:      ws.Resume,
+		Sequence:    ws.Sequence,
+		FileMode:    ws.FileMode,
+		DirMode:     ws.DirMode,
+		MkdirAll:    ws.MkdirAll,
+		Flags:       ws.Flags,
+		SumSuffix:   ws.SumSuffix,
+		IndexEvery:  ws.IndexEvery,
+		TempSuffix:  ws.TempSuffix,
+		Sortable:    ws.Sortable,
+		Precise:     ws.Precise,
+		AlignSplit:  ws.AlignSplit,
+		Context:     ws.Context,
+		OnCreate:    ws.OnCreate,
+		Factory:     ws.Factory,
+		OnClosed:    ws.OnClosed,
+		OnRotate:    ws.OnRotate,
+		OnError:     ws.OnError,
+		OnFinalized: ws.OnFinalized,
+		RouteKey:    ws.RouteKey,
+		Hash:        ws.Hash,
+		Header:      ws.Header,
+		Footer:      ws.Footer,
+		Retention:   ws.Retention,
+		Rotation:    splitcore.Fork(ws.Rotation),
+		SoftLimit:   ws.SoftLimit,
+		OnSoftLimit: ws.OnSoftLimit,
+		MissingDir:  ws.MissingDir,
+		FallbackDir: ws.FallbackDir,
+		WarmDir:     ws.WarmDir,
+		WarmAfter:   ws.WarmAfter,
+		HotBytes:    ws.HotBytes,
+
+		RotateBefore: ws.RotateBefore,
+		RawRetention: ws.RawRetention,
+
+		MaxTotalBytes: ws.MaxTotalBytes,
+		PruneToQuota:  ws.PruneToQuota,
+
+		Key:     ws.Key,
+		KeyFunc: ws.KeyFunc,
+
+		NextRotation: ws.NextRotation,
+
+		OnRotateContext: ws.OnRotateContext,
+		OnClosedContext: ws.OnClosedContext,
+
+		ShouldCompress: ws.ShouldCompress,
+	}
+}
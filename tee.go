@@ -0,0 +1,82 @@
+package writesplitter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TeeError holds the errors from the WriteSplitters of a Tee, indexed like
+// Tee.Splitters, nil for those that succeeded
+type TeeError []error
+
+func (te TeeError) Error() string {
+	var msgs []string
+	for i, e := range te {
+		if e != nil {
+			msgs = append(msgs, strconv.Itoa(i)+": "+e.Error())
+		}
+	}
+	return "WriteSplitter: tee: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors that occurred, for errors.Is and errors.As
+func (te TeeError) Unwrap() []error {
+	var errs []error
+	for _, e := range te {
+		if e != nil {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// Tee writes every Write to each of several WriteSplitters, each with its own
+// policies, e.g. a byte limited debug stream and a line limited audit stream
+type Tee struct {
+	Splitters []*WriteSplitter
+}
+
+// NewTee returns a Tee of splitters
+func NewTee(splitters ...*WriteSplitter) *Tee {
+	return &Tee{Splitters: splitters}
+}
+
+// Write writes p to every splitter, even if some fail. It returns the fewest
+// bytes any splitter took and, if any failed, a TeeError.
+func (t *Tee) Write(p []byte) (int, error) {
+	n := len(p)
+	var errs TeeError
+	for i, ws := range t.Splitters {
+		m, e := ws.Write(p)
+		if m < n {
+			n = m
+		}
+		if e != nil {
+			if errs == nil {
+				errs = make(TeeError, len(t.Splitters))
+			}
+			errs[i] = e
+		}
+	}
+	if errs != nil {
+		return n, errs
+	}
+	return n, nil
+}
+
+// Close closes every splitter and returns a TeeError if any failed
+func (t *Tee) Close() error {
+	var errs TeeError
+	for i, ws := range t.Splitters {
+		if e := ws.Close(); e != nil && e != ErrNotAFile {
+			if errs == nil {
+				errs = make(TeeError, len(t.Splitters))
+			}
+			errs[i] = e
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package writesplitter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	tests := []struct {
+		name   string
+		closed []bool // which of the splitters are closed before the Write
+		fail   []int  // the indexes expected in the TeeError
+	}{
+		{"none", nil, nil},
+		{"all fine", []bool{false, false}, nil},
+		{"one failing", []bool{false, true, false}, []int{1}},
+		{"all failing", []bool{true, true}, []int{0, 1}},
+	}
+	for _, tt := range tests {
+		var splitters []*WriteSplitter
+		for _, closed := range tt.closed {
+			ws := LineSplitter(0, t.TempDir(), "x")
+			if closed {
+				ws.Close()
+			}
+			splitters = append(splitters, ws)
+		}
+		tee := NewTee(splitters...)
+
+		n, e := tee.Write([]byte("a\n"))
+		if tt.fail == nil {
+			if e != nil || n != 2 {
+				t.Errorf("%s: got %d, %v", tt.name, n, e)
+			}
+		} else {
+			var te TeeError
+			if !errors.As(e, &te) || n != 0 {
+				t.Errorf("%s: got %d, %v, want a TeeError", tt.name, n, e)
+				continue
+			}
+			for _, i := range tt.fail {
+				if !errors.Is(te[i], ErrClosed) {
+					t.Errorf("%s: splitter %d got %v", tt.name, i, te[i])
+				}
+			}
+			if !errors.Is(e, ErrClosed) {
+				t.Errorf("%s: the TeeError doesn't unwrap", tt.name)
+			}
+		}
+		if e := tee.Close(); e != nil {
+			t.Errorf("%s: Close: %v", tt.name, e)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package writesplitter
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tier moves completed files from Dir to WarmDir, oldest first, once they are
+// older than WarmAfter or for as long as the files left in Dir add up to more
+// than HotBytes. Neither the open file nor one still being finalized is moved,
+// though the open file does count toward HotBytes.
+func (ws *WriteSplitter) tier() error {
+	if ws.WarmDir == "" || (ws.WarmAfter <= 0 && ws.HotBytes <= 0) {
+		return nil
+	}
+
+	type hotFile struct {
+		i    int
+		size int64
+		mod  time.Time
+	}
+
+	warm := filepath.Clean(ws.WarmDir)
+	var hot []hotFile
+	var total int64
+	for i, rec := range ws.files {
+		if filepath.Dir(rec.name) == warm {
+			continue
+		}
+		stat, e := os.Stat(rec.name)
+		if e != nil {
+			continue
+		}
+		total += stat.Size()
+		if ws.finalized(rec.name) {
+			hot = append(hot, hotFile{i, stat.Size(), stat.ModTime()})
+		}
+	}
+
+	var err error
+	now := time.Now()
+	for _, h := range hot { // ws.files is in creation order, so oldest first
+		old := ws.WarmAfter > 0 && now.Sub(h.mod) >= ws.WarmAfter
+		over := ws.HotBytes > 0 && total > ws.HotBytes
+		if !old && !over {
+			continue
+		}
+
+		dst := filepath.Join(warm, filepath.Base(ws.files[h.i].name))
+		if e := moveFile(ws.files[h.i].name, dst); e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		for _, side := range ws.sidecars(ws.files[h.i].name) {
+			if e := moveFile(side, filepath.Join(warm, filepath.Base(side))); e != nil && !os.IsNotExist(e) && err == nil {
+				err = e
+			}
+		}
+		ws.files[h.i].name = dst
+		ws.files[h.i].durable = false // its new name isn't, until the next Barrier
+		total -= h.size
+	}
+	return err
+}
+
+// moveFile renames src to dst, falling back to copy and remove when the two
+// are on different devices
+func moveFile(src, dst string) error {
+	if e := os.Rename(src, dst); e == nil {
+		return nil
+	}
+
+	in, e := os.Open(src)
+	if e != nil {
+		return e
+	}
+	defer in.Close()
+
+	out, e := os.Create(dst)
+	if e != nil {
+		return e
+	}
+
+	if _, e = io.Copy(out, in); e != nil {
+		out.Close()
+		os.Remove(dst)
+		return e
+	}
+	if e = out.Close(); e != nil {
+		os.Remove(dst)
+		return e
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,109 @@
+package writesplitter
+
+import "text/template"
+
+// ConfigError describes a WriteSplitter configuration that can't work as
+// intended, see Validate
+type ConfigError struct {
+	Field  string // the offending field
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return "WriteSplitter: invalid " + e.Field + ": " + e.Reason
+}
+
+// Validate checks the configuration for settings that contradict each other
+// or can never take effect and returns a *ConfigError for the first one found.
+// If Strict is set, Write calls Validate before creating the first file.
+func (ws *WriteSplitter) Validate() error {
+	switch {
+	case ws.Limit < 0:
+		return &ConfigError{"Limit", "must not be negative"}
+	case ws.Bytes && ws.Limit == 0:
+		return &ConfigError{"Bytes", "splitting by bytes requires a Limit"}
+	case ws.Precise && !ws.Bytes:
+		return &ConfigError{"Precise", "only a byte Limit can be split within a Write"}
+	case ws.AlignSplit && !ws.Precise:
+		return &ConfigError{"AlignSplit", "set without Precise"}
+	case ws.Buffer < 0:
+		return &ConfigError{"Buffer", "must not be negative"}
+	case ws.FlushEvery > 0 && ws.Buffer == 0:
+		return &ConfigError{"FlushEvery", "there is no Buffer to flush"}
+	case ws.Rotation != nil && (ws.Limit > 0 || ws.Interval > 0 || ws.NextRotation != nil):
+		return &ConfigError{"Rotation", "replaces Limit and Interval, which must be left at zero"}
+	case ws.Sortable && ws.Template != "":
+		return &ConfigError{"Sortable", "Template names files instead"}
+	case ws.MaxTotalBytes < 0:
+		return &ConfigError{"MaxTotalBytes", "must not be negative"}
+	case ws.PruneToQuota && ws.MaxTotalBytes == 0:
+		return &ConfigError{"PruneToQuota", "there is no MaxTotalBytes to prune to"}
+	case ws.Factory != nil && ws.MaxTotalBytes > 0:
+		return &ConfigError{"MaxTotalBytes", "files from a Factory aren't on disk to count"}
+	case ws.Key != nil && ws.KeyFunc != nil:
+		return &ConfigError{"Key", "KeyFunc provides the keys instead"}
+	case ws.Key != nil && len(ws.Key) != 16 && len(ws.Key) != 24 && len(ws.Key) != 32:
+		return &ConfigError{"Key", "must be 16, 24 or 32 bytes"}
+	case ws.encrypted() && (ws.Compress || ws.Resume || ws.IndexEvery > 0):
+		return &ConfigError{"Key", "encrypted files can't be compressed, resumed or indexed"}
+	case ws.encrypted() && ws.SumSuffix != "":
+		return &ConfigError{"SumSuffix", "Hash digests the plaintext, which wouldn't verify against an encrypted file"}
+	case ws.NextRotation != nil && ws.Interval > 0:
+		return &ConfigError{"NextRotation", "replaces Interval, which must be left at zero"}
+	case ws.Interval < 0:
+		return &ConfigError{"Interval", "must not be negative"}
+	case ws.PerWrite && (ws.Limit > 0 || ws.Interval > 0 || ws.NextRotation != nil):
+		return &ConfigError{"PerWrite", "every Write gets its own file, Limit and Interval can't apply"}
+	case ws.PreOpen && ws.Limit == 0:
+		return &ConfigError{"PreOpen", "there is no Limit to open ahead of"}
+	case ws.SoftLimit < 0 || ws.SoftLimit > 1:
+		return &ConfigError{"SoftLimit", "must be a fraction of Limit between 0 and 1"}
+	case ws.SoftLimit > 0 && ws.Limit == 0:
+		return &ConfigError{"SoftLimit", "there is no Limit to take a fraction of"}
+	case ws.SoftLimit > 0 && ws.OnSoftLimit == nil:
+		return &ConfigError{"SoftLimit", "set without OnSoftLimit"}
+	case ws.Factory != nil && (ws.Compress || ws.SizeInName || ws.RemoveEmpty || ws.OnClosed != nil || ws.OnClosedContext != nil || ws.OnCreate != nil):
+		return &ConfigError{"Factory", "its files aren't on disk to compress, rename, remove or reopen"}
+	case ws.Factory != nil && ws.Resume:
+		return &ConfigError{"Resume", "files from a Factory aren't on disk to resume"}
+	case ws.ShouldCompress != nil && !ws.Compress:
+		return &ConfigError{"ShouldCompress", "there is no Compress to decide on"}
+	case ws.Resume && ws.Footer != nil:
+		return &ConfigError{"Resume", "appending would leave the Footer in the middle of the file"}
+	case ws.Resume && ws.Sequence:
+		return &ConfigError{"Resume", "a Sequence carries on from Import, not from the file resumed"}
+	case ws.Resume && ws.Template != "":
+		return &ConfigError{"Resume", "files named by a Template can't be told from anyone else's"}
+	case ws.SumSuffix != "" && ws.Hash == nil:
+		return &ConfigError{"SumSuffix", "there is no Hash to write"}
+	case ws.Factory != nil && ws.SumSuffix != "":
+		return &ConfigError{"SumSuffix", "files from a Factory aren't on disk to write alongside"}
+	case ws.KeepRaw && !ws.Compress:
+		return &ConfigError{"KeepRaw", "there is no compressed copy to keep the raw file alongside"}
+	case ws.RawRetention != nil && !ws.KeepRaw:
+		return &ConfigError{"RawRetention", "there are no raw copies without KeepRaw"}
+	case ws.Factory != nil && ws.IndexEvery > 0:
+		return &ConfigError{"IndexEvery", "files from a Factory aren't on disk to index"}
+	case ws.Factory != nil && ws.TempSuffix != "":
+		return &ConfigError{"TempSuffix", "files from a Factory aren't on disk to rename"}
+	case ws.Factory != nil && ws.Link != "":
+		return &ConfigError{"Link", "files from a Factory aren't on disk to link to"}
+	case ws.Factory != nil && (ws.Ephemeral || ws.Retention != nil || ws.WarmDir != ""):
+		return &ConfigError{"Factory", "its files aren't on disk to delete or move"}
+	case ws.MissingDir == FallbackMissingDir && ws.FallbackDir == "":
+		return &ConfigError{"MissingDir", "falling back requires a FallbackDir"}
+	case ws.WarmDir == "" && (ws.WarmAfter > 0 || ws.HotBytes > 0):
+		return &ConfigError{"WarmDir", "WarmAfter and HotBytes require a WarmDir"}
+	case ws.WarmDir != "" && ws.WarmAfter <= 0 && ws.HotBytes <= 0:
+		return &ConfigError{"WarmDir", "set without WarmAfter or HotBytes"}
+	case ws.WarmDir != "" && CheckDir(ws.WarmDir) != nil:
+		return &ConfigError{"WarmDir", ErrNotADir.Error()}
+	}
+
+	if ws.Template != "" {
+		if _, e := template.New("filename").Parse(ws.Template); e != nil {
+			return &ConfigError{"Template", e.Error()}
+		}
+	}
+	return nil
+}
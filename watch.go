@@ -0,0 +1,136 @@
+package writesplitter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInterval is returned by Watch for an interval that isn't positive
+var ErrInterval = errors.New("WriteSplitter: watch interval must be positive")
+
+// Watch polls dir every interval and sends the path of each completed file
+// whose name starts with prefix, whether it was written by this process or
+// another. Index sidecars are left out, as are files with any of the suffixes
+// in skip, e.g. the SumSuffix of the writer.
+//
+// temp is the TempSuffix of the writer, if any, which marks the files still
+// being written. Without one, the most recently modified file that has been
+// written to is taken to be the one still being written, and empty files to
+// have been opened ahead of time, see PreOpen; neither is sent.
+//
+// A file is only sent once its size and modification time have held for a
+// whole interval, so one still being finalized isn't sent half done. Files
+// already completed when Watch is called are not sent, nor is any file sent
+// twice unless it is removed and then created again. The channel is closed
+// once ctx is done.
+func Watch(ctx context.Context, dir, prefix, temp string, interval time.Duration, skip ...string) (<-chan string, error) {
+	if interval <= 0 {
+		return nil, ErrInterval
+	}
+
+	ch := make(chan string)
+	skip = append([]string{IndexSuffix, temp}, skip...)
+	completed := func() ([]watched, map[string]bool) { return completed(dir, prefix, temp == "", skip) }
+	seen := make(map[string]bool)
+	files, _ := completed()
+	for _, f := range files {
+		seen[f.name] = true
+	}
+
+	go func() {
+		defer close(ch)
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		last := make(map[string]watched) // as of the previous poll
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+			}
+
+			files, present := completed()
+			for name := range seen { // removed, e.g. by Retention, so seen doesn't grow for good
+				if present != nil && !present[name] { // nil if dir couldn't be read
+					delete(seen, name)
+				}
+			}
+			now := make(map[string]watched)
+			for _, f := range files {
+				if seen[f.name] {
+					continue
+				}
+				now[f.name] = f
+				if prev, ok := last[f.name]; !ok || prev.size != f.size || !prev.mod.Equal(f.mod) { // not settled yet
+					continue
+				}
+				seen[f.name] = true
+				select {
+				case ch <- f.name:
+				case <-ctx.Done():
+					return
+				}
+			}
+			last = now
+		}
+	}()
+	return ch, nil
+}
+
+// watched is a file as Watch last saw it
+type watched struct {
+	name string
+	size int64
+	mod  time.Time
+}
+
+// completed returns the files in dir starting with prefix and not ending in
+// any of skip, oldest first, along with the names of all such files. If guess
+// is set, empty files and the most recently modified of the rest are left out
+// of the first, though not the second.
+func completed(dir, prefix string, guess bool, skip []string) ([]watched, map[string]bool) {
+	entries, e := os.ReadDir(dir)
+	if e != nil {
+		return nil, nil
+	}
+
+	var files []watched
+	present := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, prefix) || hasSuffix(name, skip) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		present[path] = true
+		info, e := entry.Info()
+		if e != nil || (guess && info.Size() == 0) {
+			continue
+		}
+		files = append(files, watched{path, info.Size(), info.ModTime()})
+	}
+	if len(files) == 0 {
+		return nil, present
+	}
+
+	sort.SliceStable(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+	if guess {
+		return files[:len(files)-1], present
+	}
+	return files, present
+}
+
+// hasSuffix reports whether name ends in any of suffixes
+func hasSuffix(name string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if s != "" && strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package writesplitter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchPreOpen(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := Watch(ctx, dir, "x", "", 10*time.Millisecond)
+
+	ws := LineSplitter(10, dir, "x")
+	ws.PreOpen, ws.IndexEvery = true, 1
+	for i := 0; i < 10; i++ {
+		ws.Write([]byte("a\n"))
+	}
+	select { // the open file is not the newest, the one opened ahead of it is
+	case name := <-ch:
+		t.Fatalf("sent %s while it was being written", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	ws.Write([]byte("a\n")) // rotates
+	first := ws.Files()[0]
+	select {
+	case name := <-ch:
+		if name != first {
+			t.Errorf("sent %s, want %s", name, first)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the completed file was never sent")
+	}
+	select {
+	case name := <-ch:
+		if strings.HasSuffix(name, IndexSuffix) {
+			t.Errorf("sent the sidecar %s", name)
+		} else {
+			t.Errorf("sent %s while it was being written", name)
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+	ws.Close()
+}
+
+func TestWatchTempSuffix(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := Watch(ctx, dir, "x", ".tmp", 10*time.Millisecond)
+
+	ws := LineSplitter(1, dir, "x")
+	ws.TempSuffix = ".tmp"
+	ws.Write([]byte("a\n"))
+	ws.Write([]byte("b\n"))
+	first := ws.Files()[0]
+	select {
+	case name := <-ch:
+		if name != first {
+			t.Errorf("sent %s, want %s", name, first)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the completed file was never sent")
+	}
+	ws.Close()
+}
+
+func TestWatchInterval(t *testing.T) {
+	if _, e := Watch(context.Background(), t.TempDir(), "x", "", 0); e != ErrInterval {
+		t.Errorf("got %v, want ErrInterval", e)
+	}
+}
+
+func TestWatchForgetsRemoved(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := Watch(ctx, dir, "x", ".tmp", 10*time.Millisecond)
+
+	name := filepath.Join(dir, "x1")
+	for i := 0; i < 2; i++ { // created, removed and created again
+		os.WriteFile(name, []byte("a\n"), 0666)
+		select {
+		case got := <-ch:
+			if got != name {
+				t.Errorf("sent %s, want %s", got, name)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("round %d: the file was never sent", i)
+		}
+		os.Remove(name)
+		time.Sleep(30 * time.Millisecond) // a poll or two to notice
+	}
+}